@@ -0,0 +1,391 @@
+package soda
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores responses of GetRequest.Get, see MemoryCache and DiskCache.
+// Implementations must be safe for use by multiple goroutines.
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, cr *CachedResponse)
+	Delete(key string)
+}
+
+// CachedResponse is a response stored in a Cache
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Stored     time.Time //When the response was stored
+	Modified   time.Time //Last modified date of the dataset when the response was stored, zero if unknown
+}
+
+// size returns the approximate memory size of the response
+func (cr *CachedResponse) size() int64 {
+	size := int64(len(cr.Body))
+	for key, vals := range cr.Header {
+		size += int64(len(key))
+		for _, val := range vals {
+			size += int64(len(val))
+		}
+	}
+	return size
+}
+
+// response creates a new http.Response from the cached response
+func (cr *CachedResponse) response() *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(cr.StatusCode),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cr.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+	}
+}
+
+// CacheKey returns the key used to cache the response of r: the endpoint with format and the query parameters
+// in a canonical order, so requests with the same parameters set in a different order use the same key.
+// Requests using an app token or credentials get a key containing a hash of them, so responses
+// of private datasets are not shared between users.
+func (r *GetRequest) CacheKey() string {
+	uv := r.URLValues()
+	keys := make([]string, 0, len(uv))
+	for key := range uv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		vals := uv[key]
+		if key != "$order" {
+			//only the order of $order values is significant
+			vals = append([]string{}, vals...)
+			sort.Strings(vals)
+		}
+		for _, val := range vals {
+			parts = append(parts, key+"="+val)
+		}
+	}
+	key := r.GetEndpoint() + "?" + strings.Join(parts, "&")
+	if cred := r.credentials(); cred != "" {
+		h := sha256.Sum256([]byte(cred))
+		key += "#" + hex.EncodeToString(h[:8])
+	}
+	return key
+}
+
+// credentials returns the app token and basic authentication credentials of r, empty if none are used
+func (r *GetRequest) credentials() string {
+	var username, password string
+	if r.client != nil {
+		username, password = r.client.Username, r.client.Password
+	}
+	if r.apptoken == "" && username == "" && password == "" {
+		return ""
+	}
+	return r.apptoken + "\x00" + username + "\x00" + password
+}
+
+// getCached returns the cached response for r or calls fetch and stores the result
+func (r *GetRequest) getCached(ctx context.Context, fetch func() (*http.Response, error)) (*http.Response, error) {
+	key := r.CacheKey()
+	if cr, ok := r.Cache.Get(key); ok {
+		if !r.CacheCheckModified || !r.datasetModifiedAfter(ctx, cr.Modified) {
+			return cr.response(), nil
+		}
+		r.Cache.Delete(key)
+	}
+
+	resp, err := fetch()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	max := cacheLimit(r.Cache)
+	if max >= 0 && resp.ContentLength > max {
+		return resp, nil
+	}
+
+	cr := &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Stored:     time.Now(),
+	}
+	cr.Modified, _ = lastModified(resp.Header)
+	resp.Body = &cacheBody{wrappedBody: wrappedBody{ReadCloser: resp.Body}, max: max, store: func(body []byte) {
+		cr.Body = body
+		r.Cache.Set(key, cr)
+	}}
+	return resp, nil
+}
+
+// cacheLimit returns the maximum size of a response stored by c, -1 if unknown
+func cacheLimit(c Cache) int64 {
+	switch c := c.(type) {
+	case *MemoryCache:
+		return c.maxBytes
+	case *DiskCache:
+		return c.maxBytes
+	}
+	return -1
+}
+
+// cacheBody is a response body which is copied while it is read and stored when it is read to the end.
+// Copying stops when the body is larger than max bytes (unless max is negative).
+type cacheBody struct {
+	wrappedBody
+	buf   bytes.Buffer
+	max   int64
+	store func(body []byte)
+	done  bool
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.wrappedBody.Read(p)
+	if b.done {
+		return n, err
+	}
+	if b.max >= 0 && int64(b.buf.Len()+n) > b.max {
+		b.done = true
+		b.buf = bytes.Buffer{}
+		return n, err
+	}
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.done = true
+		b.store(b.buf.Bytes())
+	}
+	return n, err
+}
+
+// datasetModifiedAfter returns if the dataset was modified after t, errors are ignored.
+// Only a single record is requested, the last modified date is taken from the response headers.
+func (r *GetRequest) datasetModifiedAfter(ctx context.Context, t time.Time) bool {
+	c := r.clone()
+	c.Format = "json"
+	c.Query.Select = nil
+	c.Query.ClearOrder()
+	c.Query.Limit = 1
+	c.Query.Offset = 0
+	resp, err := c.execute(withOp(ctx, OpModified))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	modified, err := lastModified(resp.Header)
+	return err == nil && modified.After(t)
+}
+
+// MemoryCache is an in-memory least recently used Cache
+type MemoryCache struct {
+	maxBytes int64
+	ttl      time.Duration
+	m        sync.Mutex
+	size     int64
+	lru      *list.List //front is most recently used
+	entries  map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key string
+	cr  *CachedResponse
+}
+
+// NewMemoryCache creates a MemoryCache using at most maxBytes, the least recently used responses are removed
+// when it is full. Responses expire after ttl, use 0 to keep them until they are removed.
+func NewMemoryCache(maxBytes int64, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the response stored for key
+func (c *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cr := e.Value.(*memoryEntry).cr
+	if expired(cr, c.ttl) {
+		c.remove(e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return cr, true
+}
+
+// Set stores cr for key, responses larger than the cache are not stored
+func (c *MemoryCache) Set(key string, cr *CachedResponse) {
+	size := cr.size()
+	if size > c.maxBytes {
+		return
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&memoryEntry{key: key, cr: cr})
+	c.size += size
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Delete removes the response stored for key
+func (c *MemoryCache) Delete(key string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+// Len returns the number of responses in the cache
+func (c *MemoryCache) Len() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.lru.Len()
+}
+
+// remove removes element e, c must be locked
+func (c *MemoryCache) remove(e *list.Element) {
+	me := c.lru.Remove(e).(*memoryEntry)
+	delete(c.entries, me.key)
+	c.size -= me.cr.size()
+}
+
+func expired(cr *CachedResponse, ttl time.Duration) bool {
+	return ttl > 0 && time.Since(cr.Stored) > ttl
+}
+
+// DiskCache is a Cache storing responses as files in a directory.
+// Multiple DiskCaches (also in different processes) can use the same directory.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+	ttl      time.Duration
+	m        sync.Mutex
+}
+
+// NewDiskCache creates a DiskCache storing responses in dir, which is created if it does not exist.
+// When the files use more than maxBytes the least recently used are removed.
+// Responses expire after ttl, use 0 to keep them until they are removed.
+func NewDiskCache(dir string, maxBytes int64, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir, maxBytes: maxBytes, ttl: ttl}, nil
+}
+
+// diskCacheExt is the extension of DiskCache files
+const diskCacheExt = ".sodacache"
+
+func (c *DiskCache) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:])+diskCacheExt)
+}
+
+// Get returns the response stored for key
+func (c *DiskCache) Get(key string) (*CachedResponse, bool) {
+	path := c.path(key)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	cr := new(CachedResponse)
+	if err := gob.NewDecoder(f).Decode(cr); err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+	if expired(cr, c.ttl) {
+		os.Remove(path)
+		return nil, false
+	}
+	//the modification time is used as last access time
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return cr, true
+}
+
+// Set stores cr for key, errors are ignored
+func (c *DiskCache) Set(key string, cr *CachedResponse) {
+	if cr.size() > c.maxBytes {
+		return
+	}
+	path := c.path(key)
+	f, err := ioutil.TempFile(c.dir, "tmp")
+	if err != nil {
+		return
+	}
+	err = gob.NewEncoder(f).Encode(cr)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	c.evict()
+}
+
+// Delete removes the response stored for key
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// evict removes the least recently used files while the cache uses more than maxBytes
+func (c *DiskCache) evict() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	cached := make([]os.FileInfo, 0, len(files))
+	size := int64(0)
+	for _, fi := range files {
+		if fi.Mode().IsRegular() && strings.HasSuffix(fi.Name(), diskCacheExt) {
+			cached = append(cached, fi)
+			size += fi.Size()
+		}
+	}
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].ModTime().Before(cached[j].ModTime())
+	})
+	for _, fi := range cached {
+		if size <= c.maxBytes {
+			break
+		}
+		if os.Remove(filepath.Join(c.dir, fi.Name())) == nil {
+			size -= fi.Size()
+		}
+	}
+}
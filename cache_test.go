@@ -0,0 +1,255 @@
+package soda
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+
+	gr1 := NewGetRequest(endpoint, apptoken)
+	gr1.Filters["farm_name"] = "Bar"
+	gr1.Filters["zipcode"] = "06111"
+	gr1.Query.Where = "item = 'Apples'"
+	gr1.Query.AddOrder("farm_name", DirAsc)
+	gr1.Query.AddOrder("item", DirDesc)
+
+	gr2 := NewGetRequest(endpoint, apptoken)
+	gr2.Query.AddOrder("farm_name", DirAsc)
+	gr2.Query.AddOrder("item", DirDesc)
+	gr2.Query.Where = "item = 'Apples'"
+	gr2.Filters["zipcode"] = "06111"
+	gr2.Filters["farm_name"] = "Bar"
+
+	if gr1.CacheKey() != gr2.CacheKey() {
+		t.Errorf("Want equal keys, have %s and %s", gr1.CacheKey(), gr2.CacheKey())
+	}
+
+	gr2.Query.ClearOrder()
+	gr2.Query.AddOrder("item", DirDesc)
+	gr2.Query.AddOrder("farm_name", DirAsc)
+	if gr1.CacheKey() == gr2.CacheKey() {
+		t.Error("Want different keys for a different order")
+	}
+
+	gr2 = gr1.clone()
+	gr2.Format = "csv"
+	if gr1.CacheKey() == gr2.CacheKey() {
+		t.Error("Want different keys for a different format")
+	}
+
+	gr2 = gr1.clone()
+	gr2.apptoken = "other"
+	if gr1.CacheKey() == gr2.CacheKey() {
+		t.Error("Want different keys for a different app token")
+	}
+
+	c1 := NewClient("https://data.ct.gov", "")
+	c1.Username, c1.Password = "user1", "secret"
+	c2 := NewClient("https://data.ct.gov", "")
+	c2.Username, c2.Password = "user2", "secret"
+	key1 := c1.NewGetRequest("hma6-9xbg").CacheKey()
+	key2 := c2.NewGetRequest("hma6-9xbg").CacheKey()
+	if key1 == key2 {
+		t.Error("Want different keys for different credentials")
+	}
+	if strings.Contains(key1, "secret") {
+		t.Errorf("Want no credentials in key %s", key1)
+	}
+}
+
+// testCache runs the tests shared by the Cache implementations
+func testCache(t *testing.T, cache Cache) {
+
+	ds := newTestDataset(20)
+	var limit atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit.Store(r.URL.Query().Get("$limit"))
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Cache = cache
+
+	get := func() string {
+		resp, err := gr.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	requests := func() int32 {
+		return atomic.SwapInt32(&ds.requests, 0)
+	}
+
+	first := get()
+	if second := get(); second != first {
+		t.Errorf("Want cached body %s, have %s", first, second)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("Want %d request, have %d", 1, n)
+	}
+
+	gr.NoCache = true
+	get()
+	if n := requests(); n != 1 {
+		t.Errorf("Want %d request when bypassing the cache, have %d", 1, n)
+	}
+	gr.NoCache = false
+
+	//a newer version of the dataset invalidates the cached response
+	gr.CacheCheckModified = true
+	get()
+	if n := requests(); n != 1 {
+		t.Errorf("Want %d request for the modified check, have %d", 1, n)
+	}
+	if l := limit.Load(); l != "1" {
+		t.Errorf("Want $limit 1 for the modified check, have %v", l)
+	}
+	ds.modified = "Fri, 05 Sep 2014 09:00:00 GMT"
+	get()
+	if n := requests(); n != 2 {
+		t.Errorf("Want %d requests after the dataset was modified, have %d", 2, n)
+	}
+	get()
+	if n := requests(); n != 1 {
+		t.Errorf("Want %d request for the modified check, have %d", 1, n)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+
+	testCache(t, NewMemoryCache(1<<20, 0))
+
+	c := NewMemoryCache(250, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(key, &CachedResponse{Body: make([]byte, 100)})
+	}
+	if _, ok := c.Get("a"); ok || c.Len() != 2 {
+		t.Errorf("Want least recently used response removed, have %d responses", c.Len())
+	}
+	c.Get("b")
+	c.Set("d", &CachedResponse{Body: make([]byte, 100)})
+	if _, ok := c.Get("c"); ok {
+		t.Error("Want c removed")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("Want b kept")
+	}
+	c.Set("e", &CachedResponse{Body: make([]byte, 300)})
+	if _, ok := c.Get("e"); ok {
+		t.Error("Want responses larger than the cache not stored")
+	}
+
+	c = NewMemoryCache(1<<20, 10*time.Millisecond)
+	c.Set("a", &CachedResponse{Stored: time.Now()})
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("Want response expired")
+	}
+}
+
+func TestCacheStreaming(t *testing.T) {
+
+	ds := newTestDataset(100)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	cache := NewMemoryCache(2000, 0)
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Cache = cache
+
+	//a response larger than the cache is returned but not stored
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) <= 2000 {
+		t.Fatalf("Want a response larger than the cache, have %d bytes", len(b))
+	}
+	if cache.Len() != 0 {
+		t.Error("Want response larger than the cache not stored")
+	}
+
+	//a response is only stored when it is read to the end
+	gr.Query.Limit = 5
+	resp, err = gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Read(make([]byte, 10))
+	resp.Body.Close()
+	if cache.Len() != 0 {
+		t.Error("Want partially read response not stored")
+	}
+	resp, err = gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if cache.Len() != 1 {
+		t.Error("Want response stored")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "sodacache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewDiskCache(dir, 1<<20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCache(t, c)
+
+	c, err = NewDiskCache(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", &CachedResponse{StatusCode: 200, Body: []byte("body"), Stored: time.Now()})
+	cr, ok := c.Get("a")
+	if !ok || string(cr.Body) != "body" {
+		t.Fatalf("Want cached response, have %v", cr)
+	}
+	c.Set("b", &CachedResponse{Stored: time.Now().Add(-2 * time.Hour)})
+	if _, ok := c.Get("b"); ok {
+		t.Error("Want response expired")
+	}
+
+	c, err = NewDiskCache(dir, 2000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"x", "y", "z"} {
+		c.Set(key, &CachedResponse{Body: make([]byte, 900), Stored: time.Now()})
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := c.Get("x"); ok {
+		t.Error("Want least recently used response removed")
+	}
+	if _, ok := c.Get("z"); !ok {
+		t.Error("Want z kept")
+	}
+}
@@ -0,0 +1,139 @@
+package soda
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ChangePolicy describes what a paginator does when the dataset is modified during a multi page fetch.
+// Changes are detected using the X-SODA2-Truth-Last-Modified or Last-Modified header of each page.
+type ChangePolicy int
+
+const (
+	// ChangeReport continues the fetch, use Changed and Changes to check if the dataset was modified
+	ChangeReport ChangePolicy = iota
+
+	// ChangeFail returns a *ChangeError for the page received after the change and for every next page,
+	// the fetch cannot be completed
+	ChangeFail
+
+	// ChangeRestart starts again from the first page and returns a *ChangeError with Restarted set,
+	// all records received before must be discarded. It cannot be used with FetchAll and Fetch.
+	ChangeRestart
+)
+
+// ErrDatasetChanged can be used with errors.Is to check for a *ChangeError
+var ErrDatasetChanged = errors.New("dataset changed during fetch")
+
+// ChangeError is returned by the paginators when the dataset changed during a fetch
+type ChangeError struct {
+	Previous  time.Time //Last modified date of the dataset at the start of the fetch
+	Current   time.Time //Last modified date of the page received
+	Restarted bool      //If the fetch was restarted from the first page
+}
+
+func (e *ChangeError) Error() string {
+	if e.Restarted {
+		return fmt.Sprintf("dataset changed during fetch (modified %s, was %s), restarted from the first page",
+			e.Current.Format(time.RFC1123), e.Previous.Format(time.RFC1123))
+	}
+	return fmt.Sprintf("dataset changed during fetch (modified %s, was %s)",
+		e.Current.Format(time.RFC1123), e.Previous.Format(time.RFC1123))
+}
+
+// Is makes ChangeError match ErrDatasetChanged
+func (e *ChangeError) Is(target error) bool {
+	return target == ErrDatasetChanged
+}
+
+// changeTracker keeps the last modified dates seen during a fetch, it can be shared by multiple paginators
+type changeTracker struct {
+	cm       sync.Mutex
+	modified time.Time
+	changes  []time.Time
+	restarts uint //number of restarts caused by ChangeRestart
+}
+
+// observe checks the last modified date in h and returns a *ChangeError if policy requires so
+func (c *changeTracker) observe(h http.Header, policy ChangePolicy) *ChangeError {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	return c.check(h, policy)
+}
+
+// observeRestarts is like observe for a page requested when the restart count was restarts.
+// If the fetch was restarted since, the page belongs to the previous run and a restarted *ChangeError is returned.
+func (c *changeTracker) observeRestarts(h http.Header, policy ChangePolicy, restarts uint) *ChangeError {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	if c.restarts != restarts {
+		return &ChangeError{Previous: c.modified, Current: c.modified, Restarted: true}
+	}
+	return c.check(h, policy)
+}
+
+// check is observe with c locked
+func (c *changeTracker) check(h http.Header, policy ChangePolicy) *ChangeError {
+	lm, err := lastModified(h)
+	if err != nil {
+		return nil //cannot detect changes without the header
+	}
+
+	if c.modified.IsZero() {
+		c.modified = lm
+		return nil
+	}
+	if lm.Equal(c.modified) {
+		return nil
+	}
+	for _, t := range c.changes {
+		if lm.Equal(t) {
+			return nil
+		}
+	}
+
+	switch policy {
+	case ChangeFail:
+		return &ChangeError{Previous: c.modified, Current: lm}
+	case ChangeRestart:
+		cerr := &ChangeError{Previous: c.modified, Current: lm, Restarted: true}
+		c.modified = lm
+		c.changes = nil
+		c.restarts++
+		return cerr
+	}
+	c.changes = append(c.changes, lm)
+	return nil
+}
+
+// Modified returns the last modified date of the dataset at the start of the fetch (or restart),
+// a zero time is returned if no pages were received yet or the header is not available
+func (c *changeTracker) Modified() time.Time {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	return c.modified
+}
+
+// Changed returns if the dataset was modified during the fetch
+func (c *changeTracker) Changed() bool {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	return len(c.changes) > 0
+}
+
+// Changes returns the last modified dates seen after the start of the fetch, in the order they were received
+func (c *changeTracker) Changes() []time.Time {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	return append([]time.Time{}, c.changes...)
+}
+
+// restartCount returns the number of times the fetch was restarted
+func (c *changeTracker) restartCount() uint {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	return c.restarts
+}
@@ -0,0 +1,263 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// changingServer serves ds and changes its last modified date after the count and first page
+func changingServer(ds *testDataset) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&ds.requests) >= 2 {
+			ds.modified = "Fri, 05 Sep 2014 10:00:00 GMT"
+		}
+		ds.ServeHTTP(w, r)
+	}))
+}
+
+func TestOffsetGetRequestChangeFail(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := changingServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ogr.OnChange = ChangeFail
+
+	resp, err := ogr.Next(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	_, err = ogr.Next(1000)
+	if !errors.Is(err, ErrDatasetChanged) {
+		t.Fatalf("Want ErrDatasetChanged, have %v", err)
+	}
+
+	//the fetch stays failed, the remaining pages are not skipped
+	requests := atomic.LoadInt32(&ds.requests)
+	_, err = ogr.Next(1000)
+	if !errors.Is(err, ErrDatasetChanged) {
+		t.Errorf("Want ErrDatasetChanged for the next page, have %v", err)
+	}
+	if n := atomic.LoadInt32(&ds.requests); n != requests {
+		t.Errorf("Want no request after the change, have %d", n-requests)
+	}
+	if ogr.IsDone() {
+		t.Error("Want IsDone false after a change")
+	}
+	if failed := ogr.Failed(); len(failed) != 1 || failed[0].Offset != 1000 {
+		t.Errorf("Want failed page at offset 1000, have %v", failed)
+	}
+}
+
+func TestOffsetGetRequestChangeAfterCount(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("$select") != "count(*)" {
+			w.Header().Set("X-SODA2-Truth-Last-Modified", "Fri, 05 Sep 2014 10:00:00 GMT")
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ogr.OnChange = ChangeFail
+
+	if _, err := ogr.Next(1000); !errors.Is(err, ErrDatasetChanged) {
+		t.Errorf("Want ErrDatasetChanged for a change after the count, have %v", err)
+	}
+}
+
+func TestOffsetGetRequestChangeRestart(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := changingServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ogr.OnChange = ChangeRestart
+	if err := ogr.FetchAll(context.Background(), 2, 1000, func(resp *http.Response) error { return nil }); err == nil {
+		t.Error("Want error for FetchAll with ChangeRestart")
+	}
+
+	pages := make([]uint, 0)
+	restarts := 0
+	for {
+		page, resp, err := ogr.NextPage(1000)
+		if err == ErrDone {
+			break
+		}
+		cerr := new(ChangeError)
+		if errors.As(err, &cerr) && cerr.Restarted {
+			restarts++
+			pages = pages[:0]
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		pages = append(pages, page.Offset)
+	}
+	if restarts != 1 || len(pages) != 3 || pages[0] != 0 {
+		t.Errorf("Want 1 restart and 3 pages, have %d restarts and pages %v", restarts, pages)
+	}
+}
+
+func TestKeysetGetRequestChangeReport(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := changingServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	kgr := NewKeysetGetRequest(gr, "")
+	for {
+		resp, err := kgr.Next(1000)
+		if err == ErrDone {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if !kgr.Changed() || len(kgr.Changes()) != 1 {
+		t.Errorf("Want 1 change, have %v", kgr.Changes())
+	}
+	if kgr.Modified().Day() != 4 {
+		t.Errorf("Want modified date of the first page, have %s", kgr.Modified())
+	}
+}
+
+func TestPartitionedGetRequestChange(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	//only the null partition sees the new version, its baseline alone cannot detect the change
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("$where"), "IS NULL") {
+			w.Header().Set("X-SODA2-Truth-Last-Modified", "Fri, 05 Sep 2014 10:00:00 GMT")
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	for _, policy := range []ChangePolicy{ChangeReport, ChangeFail} {
+		gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+		p, err := NewPartitionedGetRequest(gr, "n", 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.OnChange = policy
+
+		err = p.Fetch(500, func(resp *http.Response) error {
+			return nil
+		})
+		if policy == ChangeFail {
+			if !errors.Is(err, ErrDatasetChanged) {
+				t.Errorf("Want ErrDatasetChanged, have %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Changed() || len(p.Changes()) != 1 {
+			t.Errorf("Want 1 change, have %v", p.Changes())
+		}
+	}
+}
+
+func TestPartitionedGetRequestChangeRestart(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 4 {
+			w.Header().Set("X-SODA2-Truth-Last-Modified", "Fri, 05 Sep 2014 10:00:00 GMT")
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	p, err := NewPartitionedGetRequest(gr, "n", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.OnChange = ChangeRestart
+
+	if err := p.Fetch(500, func(resp *http.Response) error { return nil }); err == nil {
+		t.Error("Want error for Fetch with ChangeRestart")
+	}
+
+	//after a restart all partitions start again from their first page
+	seen := make(map[string]int)
+	fetch := func() error {
+		for _, k := range p.Partitions() {
+			for {
+				resp, err := k.Next(500)
+				if err == ErrDone {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				results := make([]map[string]string, 0)
+				err = json.NewDecoder(resp.Body).Decode(&results)
+				resp.Body.Close()
+				if err != nil {
+					return err
+				}
+				for _, r := range results {
+					seen[r[":id"]]++
+				}
+			}
+		}
+		return nil
+	}
+	restarts := 0
+	for err := fetch(); err != nil; err = fetch() {
+		cerr := new(ChangeError)
+		if !errors.As(err, &cerr) || !cerr.Restarted {
+			t.Fatal(err)
+		}
+		restarts++
+		seen = make(map[string]int)
+	}
+	if restarts != 1 {
+		t.Errorf("Want %d restart, have %d", 1, restarts)
+	}
+	if len(seen) != len(ds.rows) {
+		t.Errorf("Want %d records, have %d", len(ds.rows), len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("Record %s received %d times", id, n)
+		}
+	}
+}
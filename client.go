@@ -0,0 +1,120 @@
+package soda
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client holds the settings shared by all requests to a Socrata domain: the HTTP client, credentials,
+// user agent, retry policy, rate limiter, interceptors and default format.
+// GetRequests created by a Client (directly or using a Dataset) use these settings for data, count,
+// metadata and export requests. It is safe for use by multiple goroutines, but must not be changed while in use.
+type Client struct {
+	Domain       string        //Domain URL, for example https://data.ct.gov
+	AppToken     string        //App token sent with every request
+	Username     string        //Username for HTTP basic authentication, for private datasets
+	Password     string        //Password for HTTP basic authentication
+	HTTPClient   *http.Client  //HTTP client used for all requests. Default: http.DefaultClient
+	UserAgent    string        //User-Agent header sent with every request
+	Retry        *RetryPolicy  //Retry policy of new requests
+	RateLimiter  *RateLimiter  //Rate limiter shared by all requests
+	Interceptors []Interceptor //Interceptors of new requests
+	Format       string        //Default format of new requests. Default: json
+}
+
+// NewClient creates a new Client for domain, for example https://data.ct.gov or data.ct.gov (https is used by default)
+func NewClient(domain, apptoken string) *Client {
+	return &Client{
+		Domain:   domain,
+		AppToken: apptoken,
+	}
+}
+
+// domainURL returns the domain with scheme and without a trailing slash
+func (c *Client) domainURL() string {
+	d := strings.TrimSuffix(c.Domain, "/")
+	if !strings.Contains(d, "://") {
+		d = "https://" + d
+	}
+	return d
+}
+
+// NewGetRequest creates a new GetRequest for dataset, which is a dataset identifier like hma6-9xbg
+// or a complete resource URL (without format)
+func (c *Client) NewGetRequest(dataset string) *GetRequest {
+	endpoint := dataset
+	if !strings.Contains(dataset, "://") {
+		endpoint = c.domainURL() + "/resource/" + dataset
+	}
+	r := NewGetRequest(endpoint, c.AppToken)
+	r.client = c
+	r.HTTPClient = c.HTTPClient
+	r.Retry = c.Retry
+	r.RateLimiter = c.RateLimiter
+	r.Interceptors = append([]Interceptor{}, c.Interceptors...)
+	r.Format = c.Format
+	return r
+}
+
+// Dataset returns the Dataset with identifier id, for example hma6-9xbg
+func (c *Client) Dataset(id string) *Dataset {
+	return &Dataset{ID: id, client: c}
+}
+
+// setHeaders sets the user agent and credentials of c on req
+func (c *Client) setHeaders(req *http.Request) {
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// Dataset is a handle for a single dataset of a Client
+type Dataset struct {
+	ID     string
+	client *Client
+}
+
+// Endpoint returns the resource URL of the dataset (without format)
+func (d *Dataset) Endpoint() string {
+	return d.client.domainURL() + "/resource/" + d.ID
+}
+
+// NewGetRequest creates a new GetRequest for the dataset
+func (d *Dataset) NewGetRequest() *GetRequest {
+	return d.client.NewGetRequest(d.ID)
+}
+
+// Metadata gets the metadata of the dataset
+func (d *Dataset) Metadata() (*Metadata, error) {
+	return d.MetadataContext(context.Background())
+}
+
+// MetadataContext is like Metadata but uses ctx for the HTTP request
+func (d *Dataset) MetadataContext(ctx context.Context) (*Metadata, error) {
+	return d.NewGetRequest().Metadata.GetContext(ctx)
+}
+
+// Count gets the total number of records in the dataset
+func (d *Dataset) Count() (uint, error) {
+	return d.CountContext(context.Background())
+}
+
+// CountContext is like Count but uses ctx for the HTTP request
+func (d *Dataset) CountContext(ctx context.Context) (uint, error) {
+	return d.NewGetRequest().CountContext(ctx)
+}
+
+// Modified returns when the dataset was last updated
+func (d *Dataset) Modified() (time.Time, error) {
+	return d.ModifiedContext(context.Background())
+}
+
+// ModifiedContext is like Modified but uses ctx for the HTTP request
+func (d *Dataset) ModifiedContext(ctx context.Context) (time.Time, error) {
+	return d.NewGetRequest().ModifiedContext(ctx)
+}
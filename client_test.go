@@ -0,0 +1,89 @@
+package soda
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+
+	ds := newTestDataset(10)
+	var m sync.Mutex
+	paths := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		paths = append(paths, r.URL.Path)
+		m.Unlock()
+
+		user, pass, _ := r.BasicAuth()
+		if r.Header.Get("X-App-Token") != "token" || r.Header.Get("User-Agent") != "test-agent" || user != "user" || pass != "secret" {
+			t.Errorf("Missing client headers for %s: %v", r.URL.Path, r.Header)
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/views/"):
+			w.Write([]byte(`{"id":"hma6-9xbg","columns":[{"fieldName":"n"}]}`))
+		case strings.HasPrefix(r.URL.Path, "/api/views/"):
+			w.Write([]byte("n\n1\n"))
+		default:
+			ds.ServeHTTP(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL+"/", "token")
+	c.UserAgent = "test-agent"
+	c.Username = "user"
+	c.Password = "secret"
+	c.Format = "csv"
+	c.Retry = DefaultRetryPolicy()
+
+	d := c.Dataset("hma6-9xbg")
+	if d.Endpoint() != ts.URL+"/resource/hma6-9xbg" {
+		t.Errorf("Want endpoint %s, have %s", ts.URL+"/resource/hma6-9xbg", d.Endpoint())
+	}
+
+	gr := d.NewGetRequest()
+	if gr.Format != "csv" || gr.Retry != c.Retry {
+		t.Error("Want client defaults on new request")
+	}
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	count, err := d.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("Want count %d, have %d", 10, count)
+	}
+
+	md, err := d.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.ID != "hma6-9xbg" || len(md.Columns) != 1 {
+		t.Errorf("Unexpected metadata %+v", md)
+	}
+
+	if _, err := gr.Export(ioutil.Discard, "csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/resource/hma6-9xbg.csv", "/resource/hma6-9xbg.json", "/views/hma6-9xbg", "/api/views/hma6-9xbg/rows.csv"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Want requests %v, have %v", want, paths)
+	}
+
+	c = NewClient("data.ct.gov", "")
+	if gr := c.NewGetRequest("hma6-9xbg"); gr.GetEndpoint() != "https://data.ct.gov/resource/hma6-9xbg.json" {
+		t.Errorf("Want https endpoint, have %s", gr.GetEndpoint())
+	}
+}
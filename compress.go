@@ -0,0 +1,146 @@
+package soda
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// acceptEncoding is the Accept-Encoding header sent for data requests
+const acceptEncoding = "gzip, deflate"
+
+// wireCounter is implemented by response bodies that know how many bytes were received over the network
+type wireCounter interface {
+	wireBytes() uint64
+}
+
+// wireBytes returns the number of bytes body received over the network, or n (the bytes read from it) if unknown
+func wireBytes(body io.Reader, n uint64) uint64 {
+	if wc, ok := body.(wireCounter); ok {
+		return wc.wireBytes()
+	}
+	return n
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddUint64(&c.n, uint64(n))
+	return n, err
+}
+
+// wrappedBody is embedded by response bodies wrapping another body, it counts the bytes read
+// and reports the bytes received over the network by the wrapped body, which may be compressed
+type wrappedBody struct {
+	io.ReadCloser
+	read uint64
+}
+
+func (b *wrappedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddUint64(&b.read, uint64(n))
+	return n, err
+}
+
+func (b *wrappedBody) wireBytes() uint64 {
+	return wireBytes(b.ReadCloser, atomic.LoadUint64(&b.read))
+}
+
+// bufferBody reads the body of resp completely and replaces it with an in memory copy,
+// which keeps reporting the number of bytes received over the network
+func bufferBody(resp *http.Response) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	wire := wireBytes(resp.Body, uint64(len(body)))
+	resp.Body = &bufferedBody{Reader: bytes.NewReader(body), wire: wire}
+	return body, nil
+}
+
+// bufferedBody is a response body read into memory by bufferBody
+type bufferedBody struct {
+	*bytes.Reader
+	wire uint64
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+func (b *bufferedBody) wireBytes() uint64 {
+	return b.wire
+}
+
+// decodedBody is a response body that is decompressed while it is read
+type decodedBody struct {
+	io.Reader
+	body io.ReadCloser
+	wire *countingReader
+}
+
+func (d *decodedBody) Close() error {
+	if c, ok := d.Reader.(io.Closer); ok {
+		c.Close()
+	}
+	return d.body.Close()
+}
+
+func (d *decodedBody) wireBytes() uint64 {
+	return atomic.LoadUint64(&d.wire.n)
+}
+
+// decodeBody replaces a gzip or deflate compressed response body with a streaming decompressor.
+// This is needed because the Go transport only decompresses responses when it set Accept-Encoding itself.
+func decodeBody(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding != "gzip" && encoding != "deflate" {
+		return nil
+	}
+
+	wire := &countingReader{r: resp.Body}
+	var r io.Reader
+	if encoding == "gzip" {
+		zr, err := gzip.NewReader(wire)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF {
+			r = strings.NewReader("") //empty body
+		} else {
+			r = zr
+		}
+	} else {
+		//deflate should use the zlib format, but some servers send raw deflate data
+		br := bufio.NewReader(wire)
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return err
+			}
+			r = zr
+		} else {
+			r = flate.NewReader(br)
+		}
+	}
+
+	resp.Body = &decodedBody{Reader: r, body: resp.Body, wire: wire}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -0,0 +1,166 @@
+package soda
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// compressHandler compresses the responses of h using encoding when the client accepts it.
+// Encoding rawdeflate sends deflate data without the zlib header.
+func compressHandler(h http.Handler, encoding string) http.Handler {
+	contentEncoding := strings.TrimPrefix(encoding, "raw")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), contentEncoding) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		var zw io.WriteCloser
+		switch encoding {
+		case "gzip":
+			zw = gzip.NewWriter(w)
+		case "deflate":
+			zw = zlib.NewWriter(w)
+		case "rawdeflate":
+			zw, _ = flate.NewWriter(w, flate.DefaultCompression)
+		}
+		defer zw.Close()
+		w.Header().Set("Content-Encoding", contentEncoding)
+		h.ServeHTTP(compressWriter{ResponseWriter: w, w: zw}, r)
+	})
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (c compressWriter) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+func TestCompression(t *testing.T) {
+
+	for _, encoding := range []string{"gzip", "deflate", "rawdeflate"} {
+		ds := newTestDataset(3000)
+		ts := httptest.NewServer(compressHandler(ds, encoding))
+
+		var last Progress
+		gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+		//a transport without automatic decompression
+		gr.HTTPClient = &http.Client{Transport: &http.Transport{DisableCompression: true}}
+		gr.Query.Limit = 3000
+		gr.MaxLimit = 1000
+		gr.Progress = func(p Progress) {
+			last = p
+		}
+
+		resp, err := gr.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := make([]map[string]string, 0)
+		err = json.NewDecoder(resp.Body).Decode(&rows)
+		resp.Body.Close()
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if len(rows) != 3000 {
+			t.Errorf("%s: want %d rows, have %d", encoding, 3000, len(rows))
+		}
+		if last.WireBytes == 0 || last.WireBytes*2 > last.Bytes {
+			t.Errorf("%s: want compressed wire bytes, have %d wire and %d decoded bytes", encoding, last.WireBytes, last.Bytes)
+		}
+	}
+}
+
+func TestCompressionDisabled(t *testing.T) {
+
+	ds := newTestDataset(10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "identity" {
+			t.Errorf("Want Accept-Encoding identity, have %s", r.Header.Get("Accept-Encoding"))
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	var last Progress
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.DisableCompression = true
+	gr.Progress = func(p Progress) {
+		last = p
+	}
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if last.Bytes == 0 || last.WireBytes != last.Bytes {
+		t.Errorf("Want equal wire and decoded bytes, have %d and %d", last.WireBytes, last.Bytes)
+	}
+}
+
+func TestCompressionBuffered(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := httptest.NewServer(compressHandler(ds, "gzip"))
+	defer ts.Close()
+
+	var last Progress
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.HTTPClient = &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	gr.Query.AddOrder(":id", DirAsc)
+	gr.Progress = func(p Progress) {
+		last = p
+	}
+
+	//the keyset and unbounded offset paginators read each page before returning it
+	kgr := NewKeysetGetRequest(gr, "")
+	ogr, err := NewUnboundedOffsetGetRequest(gr, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := map[string]func() (*http.Response, error){
+		"keyset":    func() (*http.Response, error) { return kgr.Next(3000) },
+		"unbounded": func() (*http.Response, error) { return ogr.Next(3000) },
+	}
+	for name, next := range next {
+		last = Progress{}
+		resp, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if last.WireBytes == 0 || last.WireBytes*2 > last.Bytes {
+			t.Errorf("%s: want compressed wire bytes, have %d wire and %d decoded bytes", name, last.WireBytes, last.Bytes)
+		}
+	}
+}
+
+func TestCompressedError(t *testing.T) {
+
+	ts := httptest.NewServer(compressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"query.soql.type-mismatch","message":"Type mismatch"}`))
+	}), "gzip"))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	_, err := gr.Get()
+	var serr *Error
+	if !errors.As(err, &serr) || serr.Code != "query.soql.type-mismatch" {
+		t.Errorf("Want decoded error, have %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package soda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNotModified is returned by Get when a conditional request is answered with 304 Not Modified,
+// the data did not change since the response the Validator was taken from
+var ErrNotModified = errors.New("not modified")
+
+// Validator contains the ETag and Last-Modified headers of a response, used for conditional requests.
+// A Validator is only used for the same URL (endpoint and query) as the response it was taken from.
+type Validator struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// responseValidator returns the Validator for resp, or nil if it has no ETag or Last-Modified header
+func responseValidator(req *http.Request, resp *http.Response) *Validator {
+	v := &Validator{
+		URL:          req.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if v.ETag == "" && v.LastModified == "" {
+		return nil
+	}
+	return v
+}
+
+// getConditional executes a conditional request using the Validator of r.
+// When Conditional is set the Validator is replaced by the one of the response.
+func (r *GetRequest) getConditional(ctx context.Context) (*http.Response, error) {
+	if err := r.checkOffset(); err != nil {
+		return nil, err
+	}
+	req, err := newRequest(ctx, r, r.URLValues().Encode())
+	if err != nil {
+		return nil, err
+	}
+	if v := r.Validator; v != nil && v.URL == req.URL.String() {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+
+	resp, err := do(r, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
+	if r.Conditional {
+		r.Validator = responseValidator(req, resp)
+	}
+	return resp, nil
+}
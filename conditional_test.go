@@ -0,0 +1,76 @@
+package soda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConditionalGet(t *testing.T) {
+
+	ds := newTestDataset(10)
+	etag := atomic.Value{}
+	etag.Store(`"v1"`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := etag.Load().(string)
+		w.Header().Set("ETag", current)
+		w.Header().Set("Last-Modified", "Tue, 01 Sep 2020 10:00:00 GMT")
+		if r.Header.Get("If-None-Match") == current {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Conditional = true
+
+	get := func() error {
+		resp, err := gr.Get()
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	if gr.Validator == nil || gr.Validator.ETag != `"v1"` || gr.Validator.LastModified == "" {
+		t.Fatalf("Want validator for v1, have %+v", gr.Validator)
+	}
+	if err := get(); err != ErrNotModified {
+		t.Errorf("Want %v, have %v", ErrNotModified, err)
+	}
+
+	etag.Store(`"v2"`)
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	if gr.Validator.ETag != `"v2"` {
+		t.Errorf("Want validator for v2, have %+v", gr.Validator)
+	}
+
+	//the validator is not used for another query
+	gr.Query.Limit = 5
+	if err := get(); err != nil {
+		t.Errorf("Want no conditional request for a different query, have %v", err)
+	}
+
+	//a given validator is used but not updated
+	given := &Validator{URL: gr.Validator.URL, ETag: `"v2"`}
+	gr.Conditional = false
+	gr.Validator = given
+	if err := get(); err != ErrNotModified {
+		t.Errorf("Want %v, have %v", ErrNotModified, err)
+	}
+	etag.Store(`"v3"`)
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	if gr.Validator != given {
+		t.Error("Want the given validator to be kept")
+	}
+}
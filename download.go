@@ -0,0 +1,230 @@
+package soda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// DownloadJob downloads all records of a GetRequest to a JSON file using a KeysetGetRequest.
+// After each page the output is synced and a checkpoint is written, so a failed job can be
+// resumed exactly where it left off by calling Run again.
+// A job is not resumed when the query or the dataset (last modified date) changed, ErrCheckpointMismatch is returned.
+type DownloadJob struct {
+	Request        *GetRequest
+	Key            string //Unique key column used for paging. Default: :id
+	PageSize       uint   //Number of records per request. Default: 10000
+	Output         string //Output file
+	CheckpointPath string //Checkpoint file. Default: Output with .checkpoint appended
+}
+
+// NewDownloadJob creates a new DownloadJob downloading all records of gr to the file output
+func NewDownloadJob(gr *GetRequest, output string) *DownloadJob {
+	return &DownloadJob{
+		Request: gr,
+		Output:  output,
+	}
+}
+
+// Checkpoint is the state of a DownloadJob as saved to disk
+type Checkpoint struct {
+	Fingerprint string    `json:"fingerprint"` //Fingerprint of the query
+	LastKey     string    `json:"last_key"`    //SoQL literal of the last key written
+	Records     uint64    `json:"records"`     //Number of records written
+	Bytes       int64     `json:"bytes"`       //Size of the output when the checkpoint was written
+	Modified    time.Time `json:"modified"`    //Last modified date of the dataset
+	Done        bool      `json:"done"`        //If the download is complete
+}
+
+// ErrCheckpointMismatch is returned by DownloadJob.Run when a job cannot be resumed
+var ErrCheckpointMismatch = errors.New("checkpoint does not match")
+
+// LoadCheckpoint reads a checkpoint file
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cp := new(Checkpoint)
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, fmt.Errorf("cannot read checkpoint %s: %v", path, err)
+	}
+	return cp, nil
+}
+
+// save writes the checkpoint to path, replacing the file only when writing succeeded
+func (cp *Checkpoint) save(path string) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (j *DownloadJob) key() string {
+	if j.Key == "" {
+		return ":id"
+	}
+	return j.Key
+}
+
+func (j *DownloadJob) checkpointPath() string {
+	if j.CheckpointPath == "" {
+		return j.Output + ".checkpoint"
+	}
+	return j.CheckpointPath
+}
+
+// fingerprint returns a hash of the endpoint, query and key of the job
+func (j *DownloadJob) fingerprint() string {
+	r := j.Request.clone()
+	r.Format = "json"
+	r.Query.Limit = 0
+	r.Query.Offset = 0
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", r.GetEndpoint(), j.key(), r.URLValues().Encode())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Run starts or resumes the job and returns when all records are downloaded
+func (j *DownloadJob) Run() error {
+	return j.RunContext(context.Background())
+}
+
+// RunContext is like Run but stops when ctx is cancelled, the job can be resumed later
+func (j *DownloadJob) RunContext(ctx context.Context) error {
+	pageSize := j.PageSize
+	if pageSize == 0 {
+		pageSize = 10000
+	}
+
+	modified, err := j.Request.ModifiedContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	cp, f, err := j.open(modified)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if cp.Done {
+		return nil
+	}
+
+	k := NewKeysetGetRequest(j.Request, j.key())
+	k.OnChange = ChangeFail
+	k.last = cp.LastKey
+	k.modified = cp.Modified
+
+	for {
+		resp, err := k.NextContext(ctx, pageSize)
+		if err == ErrDone {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rows := make([]json.RawMessage, 0)
+		err = json.NewDecoder(resp.Body).Decode(&rows)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if cp.Records > 0 {
+				if _, err := f.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			if _, err := f.Write(row); err != nil {
+				return err
+			}
+			cp.Records++
+		}
+		if err := j.commit(cp, f, k.LastKey()); err != nil {
+			return err
+		}
+	}
+
+	if _, err := f.Write([]byte("]")); err != nil {
+		return err
+	}
+	cp.Done = true
+	return j.commit(cp, f, cp.LastKey)
+}
+
+// open opens the output for a new or resumed job and returns the checkpoint to continue from
+func (j *DownloadJob) open(modified time.Time) (*Checkpoint, *os.File, error) {
+	fingerprint := j.fingerprint()
+
+	cp, err := LoadCheckpoint(j.checkpointPath())
+	if os.IsNotExist(err) {
+		f, err := os.Create(j.Output)
+		if err != nil {
+			return nil, nil, err
+		}
+		cp = &Checkpoint{Fingerprint: fingerprint, Modified: modified}
+		if _, err := f.Write([]byte("[")); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		if err := j.commit(cp, f, ""); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return cp, f, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cp.Fingerprint != fingerprint {
+		return nil, nil, fmt.Errorf("%w: query changed", ErrCheckpointMismatch)
+	}
+	if !cp.Done && !cp.Modified.Equal(modified) {
+		return nil, nil, fmt.Errorf("%w: dataset modified %s, was %s", ErrCheckpointMismatch,
+			modified.Format(time.RFC1123), cp.Modified.Format(time.RFC1123))
+	}
+
+	f, err := os.OpenFile(j.Output, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	//remove anything written after the checkpoint
+	if err := f.Truncate(cp.Bytes); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(cp.Bytes, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return cp, f, nil
+}
+
+// commit syncs the output and saves the checkpoint
+func (j *DownloadJob) commit(cp *Checkpoint, f *os.File, lastKey string) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	cp.Bytes = pos
+	cp.LastKey = lastKey
+	return cp.save(j.checkpointPath())
+}
@@ -0,0 +1,100 @@
+package soda
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDownloadJobResume(t *testing.T) {
+
+	ds := newTestDataset(2500)
+	fail := int32(1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//fail the third page of the first run
+		if atomic.LoadInt32(&ds.requests) == 3 && atomic.CompareAndSwapInt32(&fail, 1, 0) {
+			http.Error(w, "connection reset", http.StatusBadGateway)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	output := filepath.Join(t.TempDir(), "farms.json")
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	job := NewDownloadJob(gr, output)
+	job.PageSize = 1000
+
+	if err := job.Run(); err == nil {
+		t.Fatal("Wanted error for the first run")
+	}
+	cp, err := LoadCheckpoint(output + ".checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Records != 2000 || cp.LastKey != "'row-01999'" || cp.Done {
+		t.Errorf("Unexpected checkpoint %+v", cp)
+	}
+
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]map[string]string, 0)
+	if err := json.Unmarshal(b, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2500 {
+		t.Fatalf("Want %d records, have %d", 2500, len(rows))
+	}
+	for i, row := range rows {
+		if row[":id"] != ds.rows[i][":id"] {
+			t.Fatalf("Want %s at row %d, have %s", ds.rows[i][":id"], i, row[":id"])
+		}
+	}
+
+	//a finished job is not run again
+	requests := atomic.LoadInt32(&ds.requests)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&ds.requests) != requests+1 {
+		t.Errorf("Want only a modified request for a finished job")
+	}
+}
+
+func TestDownloadJobMismatch(t *testing.T) {
+
+	ds := newTestDataset(100)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	output := filepath.Join(t.TempDir(), "farms.json")
+	cp := &Checkpoint{Fingerprint: "other query"}
+	if err := cp.save(output + ".checkpoint"); err != nil {
+		t.Fatal(err)
+	}
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	job := NewDownloadJob(gr, output)
+	if err := job.Run(); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("Want ErrCheckpointMismatch, have %v", err)
+	}
+
+	cp.Fingerprint = job.fingerprint()
+	if err := cp.save(output + ".checkpoint"); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Run(); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("Want ErrCheckpointMismatch for a modified dataset, have %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package soda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Error categories, use errors.Is to check if an *Error belongs to a category
+var (
+	// ErrNotFound is used for 404 responses, the dataset or view does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrThrottled is used for 429 responses, too many requests were done
+	ErrThrottled = errors.New("throttled")
+
+	// ErrAuth is used for 401 and 403 responses, the app token or credentials are invalid or not allowed
+	ErrAuth = errors.New("not authorized")
+
+	// ErrBadQuery is used for 400 responses and query errors, for example a column that does not exist
+	ErrBadQuery = errors.New("bad query")
+
+	// ErrServer is used for 5xx responses
+	ErrServer = errors.New("server error")
+)
+
+// Error is returned for SODA responses with an error status.
+// Use errors.As to get the details and errors.Is to check the category, for example errors.Is(err, ErrThrottled).
+type Error struct {
+	StatusCode int             //HTTP status code
+	Code       string          //Socrata error code, for example query.soql.no-such-column
+	Message    string          //Socrata error message
+	Data       json.RawMessage //Additional error data, if present
+	Method     string          //Request method
+	URL        string          //Request URL
+	RequestID  string          //Value of the X-Socrata-RequestId header, use it when contacting Socrata support
+	Body       []byte          //Raw response body
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = string(e.Body)
+	}
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	return fmt.Sprintf("SODA error %d:\nURL: %s %s\nResponse: %s", e.StatusCode, e.Method, e.URL, msg)
+}
+
+// Is reports if the error belongs to category target
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrThrottled:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrBadQuery:
+		return e.StatusCode == http.StatusBadRequest || strings.HasPrefix(e.Code, "query.")
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// maxErrorBody is the maximum number of bytes of an error response that is read
+const maxErrorBody = 1 << 20
+
+// newError creates an *Error from an error response and closes its body
+func newError(req *http.Request, resp *http.Response) error {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		return err
+	}
+
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		RequestID:  resp.Header.Get("X-Socrata-RequestId"),
+		Body:       body,
+	}
+	if e.Method == "" {
+		e.Method = "GET"
+	}
+
+	//SODA 2 uses code and message, older endpoints errorCode
+	v := struct {
+		Code      string          `json:"code"`
+		ErrorCode string          `json:"errorCode"`
+		Message   string          `json:"message"`
+		Data      json.RawMessage `json:"data"`
+	}{}
+	if json.Unmarshal(body, &v) == nil {
+		e.Code = v.Code
+		if e.Code == "" {
+			e.Code = v.ErrorCode
+		}
+		e.Message = v.Message
+		if len(v.Data) > 0 && !isNull(v.Data) {
+			e.Data = v.Data
+		}
+	}
+	return e
+}
@@ -0,0 +1,78 @@
+package soda
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Socrata-RequestId", "abc123")
+		if strings.HasPrefix(r.URL.Path, "/views/") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"not_found","error":true,"message":"Cannot find view with id hma6-9xbg"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"query.soql.no-such-column","error":true,"message":"No such column: nope","data":{"column":"nope"}}`))
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.Select = []string{"nope"}
+	_, err := gr.Get()
+
+	var serr *Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("Want *Error, have %T", err)
+	}
+	if serr.StatusCode != 400 || serr.Code != "query.soql.no-such-column" || serr.Message != "No such column: nope" {
+		t.Errorf("Unexpected error %#v", serr)
+	}
+	if string(serr.Data) != `{"column":"nope"}` || serr.RequestID != "abc123" {
+		t.Errorf("Want data and request ID, have %s and %s", serr.Data, serr.RequestID)
+	}
+	if !strings.Contains(serr.URL, "/resource/hma6-9xbg.json") {
+		t.Errorf("Want request URL, have %s", serr.URL)
+	}
+	if !errors.Is(err, ErrBadQuery) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrServer) {
+		t.Error("Want only ErrBadQuery")
+	}
+
+	_, err = gr.Metadata.Get()
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &serr) || serr.Code != "not_found" {
+		t.Errorf("Want not found *Error, have %v", err)
+	}
+}
+
+func TestErrorCategories(t *testing.T) {
+
+	tests := []struct {
+		err  *Error
+		want []error
+	}{
+		{&Error{StatusCode: 404}, []error{ErrNotFound}},
+		{&Error{StatusCode: 429}, []error{ErrThrottled}},
+		{&Error{StatusCode: 401}, []error{ErrAuth}},
+		{&Error{StatusCode: 403}, []error{ErrAuth}},
+		{&Error{StatusCode: 400}, []error{ErrBadQuery}},
+		{&Error{StatusCode: 500, Code: "query.execution.queryTimeout"}, []error{ErrBadQuery, ErrServer}},
+		{&Error{StatusCode: 503}, []error{ErrServer}},
+	}
+	all := []error{ErrNotFound, ErrThrottled, ErrAuth, ErrBadQuery, ErrServer}
+	for _, test := range tests {
+		for _, cat := range all {
+			want := false
+			for _, w := range test.want {
+				want = want || w == cat
+			}
+			if errors.Is(test.err, cat) != want {
+				t.Errorf("Want status %d code %q is %v: %t", test.err.StatusCode, test.err.Code, cat, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package soda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportResult describes a completed export
+type ExportResult struct {
+	Bytes   int64  //Size of the complete export
+	Written int64  //Number of bytes downloaded by this call
+	SHA256  string //Hex encoded SHA-256 checksum of the complete export
+	Resumed bool   //If an earlier partial download was resumed
+}
+
+// exportFormats are the formats supported by the export endpoint
+var exportFormats = map[string]bool{"csv": true, "json": true, "xml": true}
+
+// exportURL returns the URL of the full dataset download in format
+func (m metadata) exportURL(format string) (string, error) {
+	if !exportFormats[format] {
+		return "", fmt.Errorf("cannot export using format %s, only csv, json and xml are supported", format)
+	}
+	if m.baseurl == "" || len(m.identifier) != 9 || m.identifier[4] != '-' {
+		return "", errors.New("cannot export, is the resource URL used correct?")
+	}
+	return fmt.Sprintf("%s/api/views/%s/rows.%s?accessType=DOWNLOAD", m.baseurl, m.identifier, format), nil
+}
+
+// Export downloads the complete dataset in format csv, json or xml to w using the Socrata export endpoint,
+// which is a lot faster than paging for full dataset dumps. Filters and Query are not used.
+// The HTTP client, app token and Progress of r are used.
+func (r *GetRequest) Export(w io.Writer, format string) (*ExportResult, error) {
+	return r.ExportContext(context.Background(), w, format)
+}
+
+// ExportContext is like Export but uses ctx for the HTTP request
+func (r *GetRequest) ExportContext(ctx context.Context, w io.Writer, format string) (*ExportResult, error) {
+	ctx = withOp(ctx, OpExport)
+	resp, err := r.exportRequest(ctx, format, 0, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportResult{Bytes: n, Written: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// ExportFile is like Export but writes to the file path.
+// If the file exists, the download is resumed using an HTTP Range request when the server supports it.
+// The modification time of the file is set to the last modified date of the export, it is used to
+// make sure a partial file is only resumed when the export did not change.
+func (r *GetRequest) ExportFile(path, format string) (*ExportResult, error) {
+	return r.ExportFileContext(context.Background(), path, format)
+}
+
+// ExportFileContext is like ExportFile but uses ctx for the HTTP request
+func (r *GetRequest) ExportFileContext(ctx context.Context, path, format string) (*ExportResult, error) {
+	ctx = withOp(ctx, OpExport)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+
+	resp, err := r.exportRequest(ctx, format, size, fi.ModTime())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &ExportResult{}
+	h := sha256.New()
+
+	switch resp.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		//the file is already complete if the size matches
+		if total, ok := contentRangeSize(resp.Header.Get("Content-Range")); !ok || total != size {
+			return nil, fmt.Errorf("cannot resume export of %d bytes, server responded with %s", size, resp.Status)
+		}
+		if err := hashFile(f, h, size); err != nil {
+			return nil, err
+		}
+		res.Bytes = size
+		res.Resumed = true
+		res.SHA256 = hex.EncodeToString(h.Sum(nil))
+		return res, nil
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != size {
+			return nil, fmt.Errorf("unexpected Content-Range %s", resp.Header.Get("Content-Range"))
+		}
+		if err := hashFile(f, h, size); err != nil {
+			return nil, err
+		}
+		res.Resumed = true
+	default:
+		if err := f.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		size = 0
+	}
+
+	n, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	res.Written = n
+	res.Bytes = size + n
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if lm, lerr := http.ParseTime(resp.Header.Get("Last-Modified")); lerr == nil {
+		os.Chtimes(path, lm, lm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return res, nil
+}
+
+// exportRequest requests the export, starting at offset when it is larger than 0.
+// The Range is only used when the export was not modified after modified (If-Range).
+func (r *GetRequest) exportRequest(ctx context.Context, format string, offset int64, modified time.Time) (*http.Response, error) {
+	u, err := r.Metadata.exportURL(format)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", modified.UTC().Format(http.TimeFormat))
+	}
+	resp, err := do(r, req)
+	if err != nil {
+		return nil, err
+	}
+	//only count records for csv, the json and xml exports are not a plain list of records
+	if format != "csv" {
+		format = "raw"
+	}
+	newProgressTracker(r.Progress, 0).wrap(resp, format)
+	return resp, nil
+}
+
+// hashFile writes the first size bytes of f to h, leaving f positioned at size
+func hashFile(f *os.File, h hash.Hash, size int64) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(h, f, size); err != nil {
+		return err
+	}
+	return nil
+}
+
+// contentRangeStart returns the first byte of a Content-Range header like "bytes 100-199/200"
+func contentRangeStart(cr string) (int64, bool) {
+	cr = strings.TrimPrefix(cr, "bytes ")
+	i := strings.Index(cr, "-")
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(cr[:i], 10, 64)
+	return n, err == nil
+}
+
+// contentRangeSize returns the complete size of a Content-Range header like "bytes */200"
+func contentRangeSize(cr string) (int64, bool) {
+	i := strings.LastIndex(cr, "/")
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(cr[i+1:], 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,82 @@
+package soda
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportFile(t *testing.T) {
+
+	content := []byte(strings.Repeat("farm_name,item\nBell Nurseries,Salad/micro greens\n", 100))
+	modified := time.Date(2014, 9, 4, 15, 1, 44, 0, time.UTC)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/views/hma6-9xbg/rows.csv" || r.URL.Query().Get("accessType") != "DOWNLOAD" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "rows.csv", modified, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	path := filepath.Join(t.TempDir(), "rows.csv")
+
+	tests := []struct {
+		name    string
+		partial []byte
+		mtime   time.Time
+		written int64
+		resumed bool
+	}{
+		{"new", nil, time.Time{}, int64(len(content)), false},
+		{"resume", content[:1000], modified, int64(len(content) - 1000), true},
+		{"changed", []byte("old export"), modified.Add(-time.Hour), int64(len(content)), false},
+		{"complete", content, modified, 0, true},
+	}
+	for _, test := range tests {
+		os.Remove(path)
+		if test.partial != nil {
+			if err := ioutil.WriteFile(path, test.partial, 0644); err != nil {
+				t.Fatal(err)
+			}
+			os.Chtimes(path, test.mtime, test.mtime)
+		}
+
+		res, err := gr.ExportFile(path, "csv")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if res.Bytes != int64(len(content)) || res.Written != test.written || res.Resumed != test.resumed || res.SHA256 != checksum {
+			t.Errorf("%s: unexpected result %+v", test.name, res)
+		}
+		b, _ := ioutil.ReadFile(path)
+		if !bytes.Equal(b, content) {
+			t.Errorf("%s: file content does not match", test.name)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	res, err := gr.Export(buf, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SHA256 != checksum || !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("Unexpected export result %+v", res)
+	}
+
+	if _, err := gr.Export(buf, "pdf"); err == nil {
+		t.Error("Wanted error for format pdf")
+	}
+}
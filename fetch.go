@@ -0,0 +1,95 @@
+package soda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// FetchAll creates an OffsetGetRequest from gr and gets all records using a pool of workers goroutines,
+// see OffsetGetRequest.FetchAll.
+func FetchAll(ctx context.Context, gr *GetRequest, workers int, pageSize uint, handler func(resp *http.Response) error) error {
+	o, err := NewOffsetGetRequestContext(ctx, gr)
+	if err != nil {
+		return err
+	}
+	return o.FetchAll(ctx, workers, pageSize, handler)
+}
+
+// FetchAll gets all remaining records in pages of pageSize records using a pool of workers goroutines.
+// handler is called for each page and may be called by multiple goroutines at the same time,
+// the response body is always closed after handler returns.
+// All workers are stopped at the first error or when ctx is cancelled, the first error is returned.
+// FetchAll cannot be used with OnChange ChangeRestart, because handler cannot tell which pages to discard.
+func (o *OffsetGetRequest) FetchAll(ctx context.Context, workers int, pageSize uint, handler func(resp *http.Response) error) error {
+	return o.FetchAllPages(ctx, workers, pageSize, func(_ Page, resp *http.Response) error {
+		return handler(resp)
+	})
+}
+
+// FetchAllPages is like FetchAll but also passes the Page to handler,
+// use this to reassemble the pages in order using an OrderedMerger.
+func (o *OffsetGetRequest) FetchAllPages(ctx context.Context, workers int, pageSize uint, handler func(page Page, resp *http.Response) error) error {
+	if workers < 1 {
+		return errors.New("cannot use less than 1 worker")
+	}
+	if pageSize == 0 {
+		return errors.New("cannot use a page size of 0")
+	}
+	if o.OnChange == ChangeRestart {
+		return errors.New("cannot use ChangeRestart with FetchAll, use NextPage")
+	}
+
+	return runWorkers(ctx, workers, func(ctx context.Context, _ int) error {
+		page, resp, err := o.NextPageContext(ctx, pageSize)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return handler(page, resp)
+	})
+}
+
+// runWorkers runs work in a loop on workers goroutines, numbered from 0, until it returns ErrDone.
+// All workers are stopped at the first other error or when ctx is cancelled, the first error is returned.
+func runWorkers(ctx context.Context, workers int, work func(ctx context.Context, worker int) error) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				err := work(ctx, worker)
+				if err == ErrDone {
+					return
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return parent.Err()
+}
@@ -0,0 +1,69 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFetchAll(t *testing.T) {
+
+	ds := newTestDataset(4500)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+
+	records := uint64(0)
+	err := FetchAll(context.Background(), gr, 4, 1000, func(resp *http.Response) error {
+		results := make([]map[string]string, 0)
+		if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+			return err
+		}
+		atomic.AddUint64(&records, uint64(len(results)))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records != 4500 {
+		t.Errorf("Want %d records, have %d", 4500, records)
+	}
+}
+
+func TestFetchAllError(t *testing.T) {
+
+	ds := newTestDataset(10000)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+
+	errStop := errors.New("stop")
+	pages := int32(0)
+	err := FetchAll(context.Background(), gr, 2, 100, func(resp *http.Response) error {
+		if atomic.AddInt32(&pages, 1) == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Want error %v, have %v", errStop, err)
+	}
+	if pages > 5 {
+		t.Errorf("Workers did not stop, %d pages handled", pages)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = FetchAll(ctx, gr, 2, 100, func(resp *http.Response) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Want error %v, have %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,68 @@
+package soda
+
+import (
+	"context"
+	"net/http"
+)
+
+// Operation names passed to interceptors in Call.Op
+const (
+	OpGet       = "Get"       //GetRequest.Get
+	OpCount     = "Count"     //GetRequest.Count
+	OpFields    = "Fields"    //GetRequest.Fields
+	OpModified  = "Modified"  //GetRequest.Modified
+	OpNext      = "Next"      //OffsetGetRequest.Next and NextPage, KeysetGetRequest.Next
+	OpRange     = "Range"     //min and max request of NewPartitionedGetRequest
+	OpMetadata  = "Metadata"  //Metadata.Get and Metadata.GetColumns
+	OpExport    = "Export"    //GetRequest.Export and ExportFile
+	opUndefined = "Undefined" //request done outside the operations above
+)
+
+type opKey struct{}
+
+// withOp returns ctx carrying operation name op
+func withOp(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, opKey{}, op)
+}
+
+// Operation returns the name of the SODA operation that created ctx, it can be used in an
+// http.RoundTripper using the context of the request
+func Operation(ctx context.Context) string {
+	if op, ok := ctx.Value(opKey{}).(string); ok {
+		return op
+	}
+	return opUndefined
+}
+
+// Call is a single HTTP request done by a SODA operation, as seen by an Interceptor
+type Call struct {
+	Op          string        //Operation name, see the Op constants
+	Request     *GetRequest   //Request doing the call, do not change it
+	HTTPRequest *http.Request //HTTP request, headers and URL can be changed before calling the next handler
+	Attempts    int           //Number of HTTP requests done for the call (including retries and polls), set by the last handler
+}
+
+// AndWhere adds clause to the $where parameter of the HTTP request, combined with an existing clause using AND
+func (c *Call) AndWhere(clause string) {
+	q := c.HTTPRequest.URL.Query()
+	q.Set("$where", andWhere(q.Get("$where"), clause))
+	c.HTTPRequest.URL.RawQuery = q.Encode()
+}
+
+// Handler executes a Call
+type Handler func(call *Call) (*http.Response, error)
+
+// Interceptor is called for every HTTP request and must call next to continue the request.
+// It can change the request before calling next and inspect or replace the response or error after it.
+// Error responses are returned as error (*Error), the response body is read after the interceptors returned.
+type Interceptor func(call *Call, next Handler) (*http.Response, error)
+
+// intercept executes call through interceptors, the first interceptor is the outermost
+func intercept(interceptors []Interceptor, call *Call, final Handler) (*http.Response, error) {
+	if len(interceptors) == 0 {
+		return final(call)
+	}
+	return interceptors[0](call, func(call *Call) (*http.Response, error) {
+		return intercept(interceptors[1:], call, final)
+	})
+}
@@ -0,0 +1,102 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+
+	ds := newTestDataset(100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "1" {
+			t.Errorf("Want X-Test header on %s", r.URL)
+		}
+		if strings.HasPrefix(r.URL.Path, "/views/") {
+			http.NotFound(w, r)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	var m sync.Mutex
+	calls := make([]string, 0)
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+	gr.Interceptors = []Interceptor{
+		func(call *Call, next Handler) (*http.Response, error) {
+			call.HTTPRequest.Header.Set("X-Test", "1")
+			resp, err := next(call)
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			var serr *Error
+			if errors.As(err, &serr) {
+				status = serr.StatusCode
+			}
+			m.Lock()
+			calls = append(calls, fmt.Sprintf("%s %d", call.Op, status))
+			m.Unlock()
+			return resp, err
+		},
+		func(call *Call, next Handler) (*http.Response, error) {
+			if call.Request == nil {
+				t.Error("Want GetRequest in call")
+			}
+			call.AndWhere("n < 50")
+			return next(call)
+		},
+	}
+
+	count, err := gr.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 50 {
+		t.Errorf("Want filtered count %d, have %d", 50, count)
+	}
+
+	gr.Query.Where = "n >= 40"
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]map[string]string, 0)
+	err = json.NewDecoder(resp.Body).Decode(&rows)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 10 {
+		t.Errorf("Want %d rows, have %d", 10, len(rows))
+	}
+
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = ogr.NextContext(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := gr.Metadata.Get(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Want %v, have %v", ErrNotFound, err)
+	}
+
+	want := []string{"Count 200", "Get 200", "Count 200", "Next 200", "Metadata 404"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("Want calls %v, have %v", want, calls)
+	}
+}
@@ -0,0 +1,191 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// KeysetGetRequest is a request getter that gets all the records using the filters and where clause from gr,
+// ordered by a unique key column. Each next page is requested using WHERE key > last key seen,
+// which is a lot faster than deep offsets and does not skip or duplicate rows when the dataset changes.
+// Pages depend on the previous page, so calls to Next are serialized when used by multiple goroutines.
+// The last modified date of the dataset is checked on every page, see OnChange.
+// The response format is always JSON.
+type KeysetGetRequest struct {
+	OnChange ChangePolicy //What to do when the dataset changes during the fetch. Default: ChangeReport
+
+	gr       *GetRequest
+	key      string
+	m        sync.Mutex
+	last     string //SoQL literal of the last key seen
+	done     bool
+	restarts uint                   //restart count of the change tracker the current pages belong to
+	parent   *PartitionedGetRequest //PartitionedGetRequest the request is a partition of, if any
+	*changeTracker
+	progress *progressTracker
+}
+
+// NewKeysetGetRequest creates a new KeysetGetRequest from gr, paging on column key which must be unique and not null.
+// If key is empty the Socrata row identifier :id is used.
+// The key column is added to Query.Select when a select is used, system fields (like :id) are always added.
+func NewKeysetGetRequest(gr *GetRequest, key string) *KeysetGetRequest {
+	if key == "" {
+		key = ":id"
+	}
+	return &KeysetGetRequest{gr: gr, key: key, changeTracker: &changeTracker{}, progress: newProgressTracker(gr.Progress, 0)}
+}
+
+// Next gets the next number of records
+func (k *KeysetGetRequest) Next(number uint) (*http.Response, error) {
+	return k.NextContext(context.Background(), number)
+}
+
+// NextContext is like Next but uses ctx for the HTTP request
+func (k *KeysetGetRequest) NextContext(ctx context.Context, number uint) (*http.Response, error) {
+	ctx = withOp(ctx, OpNext)
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if restarts := k.restartCount(); restarts != k.restarts {
+		//another partition restarted the fetch
+		k.restart(restarts)
+	}
+	if k.done {
+		return nil, ErrDone
+	}
+	if number == 0 {
+		return nil, errors.New("cannot get a page of 0 records")
+	}
+
+	restarts := k.restarts
+	r := k.request(number)
+	resp, err := get(ctx, r, r.URLValues().Encode())
+	if err != nil {
+		return nil, err
+	}
+	body, err := bufferBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	//another partition may have restarted the fetch while the page was requested
+	if cerr := k.observeRestarts(resp.Header, k.changePolicy(), restarts); cerr != nil {
+		if cerr.Restarted {
+			k.restart(k.restartCount())
+		}
+		return nil, cerr
+	}
+
+	rows := make([]map[string]json.RawMessage, 0)
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		last, err := keyLiteral(rows[len(rows)-1][k.key])
+		if err != nil {
+			return nil, fmt.Errorf("cannot use key %s: %v", k.key, err)
+		}
+		k.last = last
+	}
+	if uint(len(rows)) < number {
+		k.done = true
+	}
+
+	k.progress.wrap(resp, "json")
+	return resp, nil
+}
+
+// restart starts again from the first page, k must be locked
+func (k *KeysetGetRequest) restart(restarts uint) {
+	k.last = ""
+	k.done = false
+	k.restarts = restarts
+}
+
+// changePolicy returns OnChange, or the OnChange of the PartitionedGetRequest for a partition
+func (k *KeysetGetRequest) changePolicy() ChangePolicy {
+	if k.parent != nil {
+		return k.parent.OnChange
+	}
+	return k.OnChange
+}
+
+// IsDone returns if we have gotten all records
+func (k *KeysetGetRequest) IsDone() bool {
+	k.m.Lock()
+	defer k.m.Unlock()
+	return k.done
+}
+
+// LastKey returns the SoQL literal of the last key seen, or an empty string if no records were received yet
+func (k *KeysetGetRequest) LastKey() string {
+	k.m.Lock()
+	defer k.m.Unlock()
+	return k.last
+}
+
+// request returns the GetRequest for the next page
+func (k *KeysetGetRequest) request(number uint) *GetRequest {
+	r := k.gr.clone()
+	r.Format = "json"
+
+	if len(r.Query.Select) > 0 || strings.HasPrefix(k.key, ":") {
+		if len(r.Query.Select) == 0 {
+			r.Query.Select = []string{"*"}
+		}
+		if !containsColumn(r.Query.Select, k.key) {
+			r.Query.Select = append(r.Query.Select, k.key)
+		}
+	}
+	if k.last != "" {
+		r.Query.Where = andWhere(r.Query.Where, fmt.Sprintf("%s > %s", k.key, k.last))
+	}
+	r.Query.ClearOrder()
+	r.Query.AddOrder(k.key, DirAsc)
+	r.Query.Limit = number
+	r.Query.Offset = 0
+	return r
+}
+
+// keyLiteral returns the raw JSON key value as SoQL literal
+func keyLiteral(raw json.RawMessage) (string, error) {
+	if isNull(raw) {
+		return "", errors.New("key value is null")
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	switch v.(type) {
+	case float64:
+		return strings.TrimSpace(string(raw)), nil
+	case string, bool:
+		return Literal(v)
+	}
+	return "", fmt.Errorf("cannot use %s as key value", raw)
+}
+
+// andWhere combines two where clauses using AND
+func andWhere(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return fmt.Sprintf("(%s) AND (%s)", a, b)
+}
+
+// containsColumn returns if column is in the select list
+func containsColumn(sel []string, column string) bool {
+	for _, s := range sel {
+		if strings.TrimSpace(s) == column {
+			return true
+		}
+	}
+	return false
+}
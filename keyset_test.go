@@ -0,0 +1,56 @@
+package soda
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeysetGetRequest(t *testing.T) {
+
+	ds := newTestDataset(2500)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.Where = "n >= 10"
+	kgr := NewKeysetGetRequest(gr, "")
+
+	seen := make(map[string]bool)
+	pages := 0
+	for {
+		resp, err := kgr.Next(1000)
+		if err == ErrDone {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := make([]map[string]string, 0)
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			if seen[r[":id"]] {
+				t.Errorf("Duplicate row %s", r[":id"])
+			}
+			seen[r[":id"]] = true
+		}
+		pages++
+	}
+
+	if len(seen) != 2490 {
+		t.Errorf("Want %d records, have %d", 2490, len(seen))
+	}
+	if pages != 3 {
+		t.Errorf("Want %d pages, have %d", 3, pages)
+	}
+	if !kgr.IsDone() || kgr.LastKey() != "'row-02499'" {
+		t.Errorf("Unexpected state done=%t last=%s", kgr.IsDone(), kgr.LastKey())
+	}
+	if gr.Query.Where != "n >= 10" || len(gr.Query.Order) != 0 {
+		t.Errorf("GetRequest was modified: %+v", gr.Query)
+	}
+}
@@ -0,0 +1,153 @@
+package soda
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxLimit is the maximum number of records per request used when GetRequest.MaxLimit is not set
+const DefaultMaxLimit = 50000
+
+func (r *GetRequest) maxLimit() uint {
+	if r.MaxLimit == 0 {
+		return DefaultMaxLimit
+	}
+	return r.MaxLimit
+}
+
+// getSplit gets Query.Limit records using multiple requests of at most MaxLimit records.
+// The first request is done immediately, the others when the body of the returned response is read.
+// If no order is set the records are ordered on :id to get consistent pages.
+func getSplit(ctx context.Context, r *GetRequest) (*http.Response, error) {
+	r = r.clone()
+	if len(r.Query.Order) == 0 {
+		r.Query.AddOrder(":id", DirAsc)
+	}
+	if r.Format == "" {
+		r.Format = "json"
+	}
+	joiner, err := newPageJoiner(r.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	sb := &splitBody{
+		ctx:       ctx,
+		r:         r,
+		joiner:    joiner,
+		offset:    r.Query.Offset,
+		remaining: r.Query.Limit,
+	}
+	resp, err := sb.next()
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.setChunk(resp.Body); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = sb
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// splitBody is the combined response body of the requests done by getSplit
+type splitBody struct {
+	ctx       context.Context
+	r         *GetRequest
+	joiner    *pageJoiner
+	offset    uint
+	remaining uint
+	body      io.ReadCloser   //current response body
+	cur       io.Reader       //current page reader
+	read      *countingReader //counts the bytes read from the current response body
+	wire      uint64          //bytes received over the network by previous chunks
+	last      bool            //no more requests are needed
+	ended     bool
+}
+
+// next does the request for the next chunk
+func (sb *splitBody) next() (*http.Response, error) {
+	limit := sb.r.maxLimit()
+	if sb.remaining < limit {
+		limit = sb.remaining
+	}
+	sb.r.Query.Offset = sb.offset
+	sb.r.Query.Limit = limit
+	sb.offset += limit
+	sb.remaining -= limit
+	return get(sb.ctx, sb.r, sb.r.URLValues().Encode())
+}
+
+// setChunk sets body as current chunk
+func (sb *splitBody) setChunk(body io.ReadCloser) error {
+	read := &countingReader{r: body}
+	r, empty, err := sb.joiner.page(read)
+	if err != nil {
+		return err
+	}
+	sb.body = body
+	sb.read = read
+	sb.cur = r
+	sb.last = empty || sb.remaining == 0
+	return nil
+}
+
+func (sb *splitBody) Read(p []byte) (int, error) {
+	for {
+		if sb.cur != nil {
+			n, err := sb.cur.Read(p)
+			if err == io.EOF {
+				sb.cur = nil
+				err = nil
+			}
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		if sb.ended {
+			return 0, io.EOF
+		}
+		if sb.body != nil {
+			sb.closeChunk()
+		}
+		if sb.last {
+			sb.cur = sb.joiner.end()
+			sb.ended = true
+			continue
+		}
+		resp, err := sb.next()
+		if err != nil {
+			return 0, err
+		}
+		if err := sb.setChunk(resp.Body); err != nil {
+			resp.Body.Close()
+			return 0, err
+		}
+	}
+}
+
+func (sb *splitBody) Close() error {
+	if sb.body != nil {
+		return sb.closeChunk()
+	}
+	return nil
+}
+
+// closeChunk closes the current response body
+func (sb *splitBody) closeChunk() error {
+	sb.wire += wireBytes(sb.body, sb.read.n)
+	err := sb.body.Close()
+	sb.body = nil
+	return err
+}
+
+func (sb *splitBody) wireBytes() uint64 {
+	if sb.body == nil {
+		return sb.wire
+	}
+	return sb.wire + wireBytes(sb.body, sb.read.n)
+}
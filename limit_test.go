@@ -0,0 +1,59 @@
+package soda
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetSplitLimit(t *testing.T) {
+
+	ds := newTestDataset(2500)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.MaxLimit = 1000
+	gr.Query.Limit = 2200
+	gr.Query.Offset = 100
+	gr.Query.AddOrder(":id", DirAsc)
+
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]map[string]string, 0)
+	err = json.NewDecoder(resp.Body).Decode(&rows)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2200 || rows[0][":id"] != "row-00100" || rows[2199][":id"] != "row-02299" {
+		t.Errorf("Unexpected result of %d rows", len(rows))
+	}
+	if n := atomic.LoadInt32(&ds.requests); n != 3 {
+		t.Errorf("Want %d requests, have %d", 3, n)
+	}
+
+	//more than available, stops after the first short page
+	gr.Format = "csv"
+	gr.Query.Limit = 10000
+	gr.Query.Offset = 0
+	resp, err = gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2501 {
+		t.Errorf("Want %d records, have %d", 2501, len(records))
+	}
+	if gr.Query.Limit != 10000 || gr.Query.Offset != 0 {
+		t.Errorf("GetRequest was modified: %+v", gr.Query)
+	}
+}
@@ -0,0 +1,376 @@
+package soda
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// OrderedMerger reassembles pages which are received out of order (for example by multiple goroutines
+// using an OffsetGetRequest) into a single stream in the original order.
+// JSON pages are merged into one valid JSON array and CSV pages are merged using only the header of the first page.
+// Use Add to add pages and read the merged result using Read, which blocks until the next page is available.
+type OrderedMerger struct {
+	joiner *pageJoiner
+	window uint
+	m      sync.Mutex
+	cond   *sync.Cond
+	pages  map[uint][]byte
+	next   uint
+	closed bool
+	err    error
+	cur    io.Reader
+	ended  bool
+}
+
+// NewOrderedMerger creates an OrderedMerger for format json or csv.
+// At most window pages are buffered, Add blocks for pages that are further ahead than window pages.
+// When pages are added by multiple goroutines window should be larger than the number of goroutines.
+func NewOrderedMerger(format string, window uint) (*OrderedMerger, error) {
+	j, err := newPageJoiner(format)
+	if err != nil {
+		return nil, err
+	}
+	if window == 0 {
+		window = 1
+	}
+	m := &OrderedMerger{
+		joiner: j,
+		window: window,
+		pages:  make(map[uint][]byte),
+	}
+	m.cond = sync.NewCond(&m.m)
+	return m, nil
+}
+
+// Add adds page number seq (starting at 0, see Page.Seq) and reads r completely.
+// Add blocks while seq is window pages or more ahead of the next page to be read.
+func (m *OrderedMerger) Add(seq uint, r io.Reader) error {
+	return m.AddContext(context.Background(), seq, r)
+}
+
+// AddContext is like Add but stops waiting and returns the error of ctx when ctx is done.
+// When adding pages from a FetchAllPages handler use Page.Context, which is cancelled when fetching fails.
+func (m *OrderedMerger) AddContext(ctx context.Context, seq uint, r io.Reader) error {
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				m.m.Lock()
+				m.cond.Broadcast()
+				m.m.Unlock()
+			case <-stop:
+			}
+		}()
+	}
+
+	m.m.Lock()
+	for m.err == nil && !m.closed && ctx.Err() == nil && seq >= m.next+m.window {
+		m.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		m.m.Unlock()
+		return err
+	}
+	err := m.check(seq)
+	m.m.Unlock()
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	if err := m.check(seq); err != nil {
+		return err
+	}
+	m.pages[seq] = body
+	m.cond.Broadcast()
+	return nil
+}
+
+// check returns if page seq can be added, m must be locked
+func (m *OrderedMerger) check(seq uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.closed {
+		return errors.New("cannot add a page to a closed OrderedMerger")
+	}
+	if _, ok := m.pages[seq]; ok || seq < m.next {
+		return fmt.Errorf("page %d was already added", seq)
+	}
+	return nil
+}
+
+// Close must be called when all pages are added, Read returns io.EOF after the last page.
+// If pages are missing Read returns an error.
+func (m *OrderedMerger) Close() error {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.closed = true
+	m.cond.Broadcast()
+	return nil
+}
+
+// CloseWithError stops the merger, Read and Add will return err
+func (m *OrderedMerger) CloseWithError(err error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+	m.cond.Broadcast()
+}
+
+// Read reads the merged pages in order
+func (m *OrderedMerger) Read(p []byte) (int, error) {
+	for {
+		if m.cur != nil {
+			n, err := m.cur.Read(p)
+			if err == io.EOF {
+				m.cur = nil
+				err = nil
+			}
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		if m.ended {
+			return 0, io.EOF
+		}
+		if err := m.nextReader(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// nextReader waits for the next page and sets it as current reader
+func (m *OrderedMerger) nextReader() error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	for {
+		if m.err != nil {
+			return m.err
+		}
+		if body, ok := m.pages[m.next]; ok {
+			delete(m.pages, m.next)
+			m.next++
+			m.cond.Broadcast()
+			r, _, err := m.joiner.page(bytes.NewReader(body))
+			if err != nil {
+				return fmt.Errorf("cannot merge page %d: %v", m.next-1, err)
+			}
+			m.cur = r
+			return nil
+		}
+		if m.closed {
+			if len(m.pages) > 0 {
+				return fmt.Errorf("page %d is missing", m.next)
+			}
+			m.cur = m.joiner.end()
+			m.ended = true
+			return nil
+		}
+		m.cond.Wait()
+	}
+}
+
+// pageJoiner joins the bodies of multiple pages into one JSON array or CSV file
+type pageJoiner struct {
+	format string
+	pages  int
+	any    bool //JSON: an element was written
+	last   byte //CSV: last byte written
+}
+
+func newPageJoiner(format string) (*pageJoiner, error) {
+	switch format {
+	case "json", "csv":
+		return &pageJoiner{format: format}, nil
+	}
+	return nil, fmt.Errorf("cannot merge pages using format %s, only json and csv are supported", format)
+}
+
+// page returns a reader for the next page which can be concatenated to the previous pages,
+// empty is true if the page contains no records
+func (j *pageJoiner) page(body io.Reader) (r io.Reader, empty bool, err error) {
+	j.pages++
+
+	if j.format == "csv" {
+		br := bufio.NewReader(body)
+		header, err := readCSVRecord(br)
+		if err != nil {
+			return nil, false, err
+		}
+		_, err = br.Peek(1)
+		empty = err == io.EOF
+
+		r = &lastByteReader{r: br, last: &j.last}
+		if j.pages == 1 {
+			if len(header) > 0 {
+				j.last = header[len(header)-1]
+			}
+			r = io.MultiReader(bytes.NewReader(header), r)
+		} else if !empty && j.last != 0 && j.last != '\n' {
+			r = io.MultiReader(strings.NewReader("\n"), r)
+		}
+		return r, empty, nil
+	}
+
+	elems, empty, err := jsonElements(body)
+	if err != nil {
+		return nil, false, err
+	}
+	if empty {
+		return strings.NewReader(""), true, nil
+	}
+	prefix := ","
+	if !j.any {
+		prefix = "["
+	}
+	j.any = true
+	return io.MultiReader(strings.NewReader(prefix), elems), false, nil
+}
+
+// end returns a reader for the end of the stream
+func (j *pageJoiner) end() io.Reader {
+	if j.format == "csv" {
+		return strings.NewReader("")
+	}
+	if !j.any {
+		return strings.NewReader("[]")
+	}
+	return strings.NewReader("]")
+}
+
+// readCSVRecord reads the raw bytes of the first CSV record (including quoted newlines) from br
+func readCSVRecord(br *bufio.Reader) ([]byte, error) {
+	record := make([]byte, 0)
+	quoted := false
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return record, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record = append(record, b)
+		switch {
+		case b == '"':
+			quoted = !quoted
+		case b == '\n' && !quoted:
+			return record, nil
+		}
+	}
+}
+
+// lastByteReader keeps track of the last byte read
+type lastByteReader struct {
+	r    io.Reader
+	last *byte
+}
+
+func (l *lastByteReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if n > 0 {
+		*l.last = p[n-1]
+	}
+	return n, err
+}
+
+// jsonElements returns a reader for the elements of the JSON array in r, without the enclosing brackets.
+// empty is true if the array has no elements.
+func jsonElements(r io.Reader) (elems io.Reader, empty bool, err error) {
+	br := bufio.NewReader(r)
+	b, err := skipSpace(br)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot read JSON array: %v", err)
+	}
+	if b != '[' {
+		return nil, false, fmt.Errorf("expected a JSON array, have %q", b)
+	}
+	b, err = skipSpace(br)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot read JSON array: %v", err)
+	}
+	if b == ']' {
+		return nil, true, nil
+	}
+	br.UnreadByte()
+	return &arrayTailReader{r: br}, false, nil
+}
+
+// skipSpace returns the first non whitespace byte
+func skipSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !isSpace(b) {
+			return b, nil
+		}
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// arrayTailReader reads r but holds back the closing bracket of the array (and trailing whitespace)
+type arrayTailReader struct {
+	r    io.Reader
+	hold []byte
+	buf  []byte
+	eof  bool
+}
+
+func (a *arrayTailReader) Read(p []byte) (int, error) {
+	for len(a.buf) == 0 {
+		if a.eof {
+			return 0, io.EOF
+		}
+		chunk := make([]byte, len(p)+1)
+		n, err := a.r.Read(chunk)
+		data := append(a.hold, chunk[:n]...)
+		a.hold = nil
+
+		i := len(data) - 1
+		for i >= 0 && isSpace(data[i]) {
+			i--
+		}
+		if i >= 0 && data[i] == ']' {
+			a.hold = append([]byte{}, data[i:]...)
+			data = data[:i]
+		}
+		a.buf = data
+
+		if err == io.EOF {
+			if len(a.hold) == 0 {
+				return 0, errors.New("unexpected end of JSON array")
+			}
+			a.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, a.buf)
+	a.buf = a.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,144 @@
+package soda
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestOrderedMergerJSON(t *testing.T) {
+
+	m, err := NewOrderedMerger("json", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.Add(1, strings.NewReader(`[{"a":"2"}, {"a":"[3]"} ]`))
+		m.Add(3, strings.NewReader("[\n{\"a\":[4]}\n]\n"))
+	}()
+	go func() {
+		defer wg.Done()
+		m.Add(0, strings.NewReader(`[{"a":"1"}]`))
+		m.Add(2, strings.NewReader(`[]`))
+	}()
+	go func() {
+		wg.Wait()
+		m.Close()
+	}()
+
+	b, err := ioutil.ReadAll(iotest.OneByteReader(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make([]map[string]interface{}, 0)
+	if err := json.Unmarshal(b, &res); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", b, err)
+	}
+	if len(res) != 4 || res[0]["a"] != "1" || res[2]["a"] != "[3]" {
+		t.Errorf("Unexpected result %s", b)
+	}
+}
+
+func TestOrderedMergerMissingPage(t *testing.T) {
+	m, _ := NewOrderedMerger("csv", 4)
+	m.Add(0, strings.NewReader("a,b\n1,2\n"))
+	m.Add(2, strings.NewReader("a,b\n5,6\n"))
+	m.Close()
+	if _, err := ioutil.ReadAll(m); err == nil {
+		t.Error("Wanted error for a missing page")
+	}
+	if _, err := NewOrderedMerger("xml", 1); err == nil {
+		t.Error("Wanted error for format xml")
+	}
+}
+
+func TestOrderedMergerFetchAll(t *testing.T) {
+
+	ds := newTestDataset(2345)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Format = "csv"
+	gr.Query.AddOrder(":id", DirAsc)
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := NewOrderedMerger("csv", 8)
+	go func() {
+		err := ogr.FetchAllPages(context.Background(), 4, 100, func(page Page, resp *http.Response) error {
+			return m.AddContext(page.Context(), page.Seq, resp.Body)
+		})
+		if err != nil {
+			m.CloseWithError(err)
+			return
+		}
+		m.Close()
+	}()
+
+	records, err := csv.NewReader(m).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2346 {
+		t.Fatalf("Want %d records, have %d", 2346, len(records))
+	}
+	for i, rec := range records[1:] {
+		if rec[0] != ds.rows[i][":id"] {
+			t.Fatalf("Want %s at row %d, have %s", ds.rows[i][":id"], i, rec[0])
+		}
+	}
+}
+
+func TestOrderedMergerFetchAllError(t *testing.T) {
+
+	ds := newTestDataset(2345)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query(); q.Get("$limit") != "" && q.Get("$offset") == "" { //first page
+			time.Sleep(200 * time.Millisecond) //let the other workers wait in AddContext
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Format = "csv"
+	gr.Query.AddOrder(":id", DirAsc)
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := NewOrderedMerger("csv", 8)
+	done := make(chan error, 1)
+	go func() {
+		done <- ogr.FetchAllPages(context.Background(), 4, 100, func(page Page, resp *http.Response) error {
+			return m.AddContext(page.Context(), page.Seq, resp.Body)
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrServer) {
+			t.Errorf("Want ErrServer, have %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("FetchAllPages did not return after a failed page")
+	}
+}
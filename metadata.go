@@ -1,9 +1,9 @@
 package soda
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -13,6 +13,7 @@ import (
 
 type metadata struct {
 	baseurl, identifier string
+	gr                  *GetRequest //request used for the HTTP client, app token and retry policy
 }
 
 func (m metadata) url() (string, error) {
@@ -22,21 +23,24 @@ func (m metadata) url() (string, error) {
 	return fmt.Sprintf("%s/views/%s", m.baseurl, m.identifier), nil
 }
 
-func (m metadata) do() (*Metadata, error) {
+func (m metadata) do(ctx context.Context) (*Metadata, error) {
 	url, err := m.url()
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.DefaultClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		b, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Received statuscode %d\nBody: %s", resp.StatusCode, b)
+	gr := m.gr
+	if gr == nil {
+		gr = &GetRequest{}
+	}
+	resp, err := do(gr, req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	md := new(Metadata)
 	err = json.NewDecoder(resp.Body).Decode(md)
@@ -48,12 +52,22 @@ func (m metadata) do() (*Metadata, error) {
 
 //Get gets the metadata struct for this dataset
 func (m metadata) Get() (*Metadata, error) {
-	return m.do()
+	return m.GetContext(context.Background())
+}
+
+//GetContext is like Get but uses ctx for the HTTP request
+func (m metadata) GetContext(ctx context.Context) (*Metadata, error) {
+	return m.do(withOp(ctx, OpMetadata))
 }
 
 //GetColumns gets only the column info from the metadata for this dataset
 func (m metadata) GetColumns() ([]Column, error) {
-	md, err := m.do()
+	return m.GetColumnsContext(context.Background())
+}
+
+//GetColumnsContext is like GetColumns but uses ctx for the HTTP request
+func (m metadata) GetColumnsContext(ctx context.Context) ([]Column, error) {
+	md, err := m.do(withOp(ctx, OpMetadata))
 	if err != nil {
 		return []Column{}, err
 	}
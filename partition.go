@@ -0,0 +1,187 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PartitionedGetRequest gets all the records using the filters and where clause from gr by splitting
+// the range of an ordered (numeric or timestamp) column into non-overlapping partitions.
+// The partitions are fetched concurrently, each using a KeysetGetRequest, so every record is returned exactly once.
+// Records where the column is null are fetched in a separate partition.
+// The last modified date of the dataset is checked on every page of all partitions against the same
+// first date, see OnChange. The OnChange of the partitions themselves is not used.
+type PartitionedGetRequest struct {
+	OnChange ChangePolicy //What to do when the dataset changes during the fetch. Default: ChangeReport
+
+	column     string
+	where      []string
+	partitions []*KeysetGetRequest
+	*changeTracker
+}
+
+// NewPartitionedGetRequest creates a new PartitionedGetRequest from gr splitting column into n partitions.
+// A request is done to determine the minimum and maximum value of column.
+// Fewer partitions are used when the range cannot be split into n parts.
+func NewPartitionedGetRequest(gr *GetRequest, column string, n int) (*PartitionedGetRequest, error) {
+	return NewPartitionedGetRequestContext(context.Background(), gr, column, n)
+}
+
+// NewPartitionedGetRequestContext is like NewPartitionedGetRequest but uses ctx for the range request
+func NewPartitionedGetRequestContext(ctx context.Context, gr *GetRequest, column string, n int) (*PartitionedGetRequest, error) {
+	if n < 1 {
+		return nil, errors.New("cannot use less than 1 partition")
+	}
+	min, max, h, err := columnRange(ctx, gr, column)
+	if err != nil {
+		return nil, err
+	}
+
+	where := make([]string, 0, n+1)
+	if min != "" || max != "" {
+		bounds, err := rangeBounds(min, max, n)
+		if err != nil {
+			return nil, fmt.Errorf("cannot partition on column %s: %v", column, err)
+		}
+		if len(bounds) == 0 {
+			where = append(where, fmt.Sprintf("%s IS NOT NULL", column))
+		}
+		for i, b := range bounds {
+			if i == 0 {
+				where = append(where, fmt.Sprintf("%s < %s", column, b))
+			} else {
+				where = append(where, fmt.Sprintf("%s >= %s AND %s < %s", column, bounds[i-1], column, b))
+			}
+			if i == len(bounds)-1 {
+				where = append(where, fmt.Sprintf("%s >= %s", column, b))
+			}
+		}
+	}
+	where = append(where, fmt.Sprintf("%s IS NULL", column))
+
+	p := &PartitionedGetRequest{column: column, where: where, changeTracker: &changeTracker{}}
+	p.observe(h, ChangeReport) //changes are detected from the moment of the range request
+
+	progress := newProgressTracker(gr.Progress, 0) //shared by all partitions
+	for _, w := range where {
+		pr := gr.clone()
+		pr.Query.Where = andWhere(gr.Query.Where, w)
+		k := NewKeysetGetRequest(pr, "")
+		k.progress = progress
+		k.changeTracker = p.changeTracker
+		k.parent = p
+		p.partitions = append(p.partitions, k)
+	}
+	return p, nil
+}
+
+// Partitions returns the KeysetGetRequest for each partition, use this to fetch the partitions yourself
+func (p *PartitionedGetRequest) Partitions() []*KeysetGetRequest {
+	return p.partitions
+}
+
+// Where returns the where clause (without the where clause of the GetRequest) of each partition
+func (p *PartitionedGetRequest) Where() []string {
+	return p.where
+}
+
+// Fetch gets all partitions concurrently in pages of pageSize records, handler is called for each page
+// and may be called by multiple goroutines at the same time. The response body is closed after handler returns.
+// Fetch stops at the first error and returns it.
+// Fetch cannot be used with OnChange ChangeRestart, because handler cannot tell which pages to discard.
+func (p *PartitionedGetRequest) Fetch(pageSize uint, handler func(resp *http.Response) error) error {
+	return p.FetchContext(context.Background(), pageSize, handler)
+}
+
+// FetchContext is like Fetch but also stops when ctx is cancelled
+func (p *PartitionedGetRequest) FetchContext(ctx context.Context, pageSize uint, handler func(resp *http.Response) error) error {
+	if p.OnChange == ChangeRestart {
+		return errors.New("cannot use ChangeRestart with Fetch, use Partitions")
+	}
+	return runWorkers(ctx, len(p.partitions), func(ctx context.Context, worker int) error {
+		resp, err := p.partitions[worker].NextContext(ctx, pageSize)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return handler(resp)
+	})
+}
+
+// columnRange returns the minimum and maximum value of column, both are empty if there are no values,
+// and the headers of the response
+func columnRange(ctx context.Context, gr *GetRequest, column string) (string, string, http.Header, error) {
+	ctx = withOp(ctx, OpRange)
+	r := gr.clone()
+	r.Format = "json"
+	r.Query.Select = []string{
+		fmt.Sprintf("min(%s) AS min", column),
+		fmt.Sprintf("max(%s) AS max", column),
+	}
+	r.Query.ClearOrder()
+	r.Query.Limit = 0
+	r.Query.Offset = 0
+
+	resp, err := r.execute(ctx)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer resp.Body.Close()
+
+	res := make([]struct {
+		Min string
+		Max string
+	}, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", "", nil, err
+	}
+	if len(res) == 0 {
+		return "", "", resp.Header, nil
+	}
+	return res[0].Min, res[0].Max, resp.Header, nil
+}
+
+// rangeBounds returns the SoQL literals splitting min to max in n ranges, without duplicates
+func rangeBounds(min, max string, n int) ([]string, error) {
+	bounds := make([]string, 0, n-1)
+	add := func(b string) {
+		if len(bounds) == 0 || bounds[len(bounds)-1] != b {
+			bounds = append(bounds, b)
+		}
+	}
+
+	fmin, err1 := strconv.ParseFloat(min, 64)
+	fmax, err2 := strconv.ParseFloat(max, 64)
+	if err1 == nil && err2 == nil {
+		for i := 1; i < n; i++ {
+			b := fmin + (fmax-fmin)*float64(i)/float64(n)
+			if b <= fmin || b > fmax {
+				continue
+			}
+			add(strconv.FormatFloat(b, 'f', -1, 64))
+		}
+		return bounds, nil
+	}
+
+	tmin, err1 := parseTime(min)
+	tmax, err2 := parseTime(max)
+	if err1 == nil && err2 == nil {
+		d := tmax.Sub(tmin)
+		for i := 1; i < n; i++ {
+			b := tmin.Add(d / time.Duration(n) * time.Duration(i)).Truncate(time.Millisecond)
+			if !b.After(tmin) {
+				continue
+			}
+			lit, _ := Literal(b)
+			add(lit)
+		}
+		return bounds, nil
+	}
+
+	return nil, fmt.Errorf("values %s and %s are not numeric or timestamps", min, max)
+}
@@ -0,0 +1,82 @@
+package soda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPartitionedGetRequest(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.Where = "n >= 100"
+
+	p, err := NewPartitionedGetRequest(gr, "n", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"n < 824.75",
+		"n >= 824.75 AND n < 1549.5",
+		"n >= 1549.5 AND n < 2274.25",
+		"n >= 2274.25",
+		"n IS NULL",
+	}
+	if !reflect.DeepEqual(p.Where(), want) {
+		t.Errorf("Want %v, have %v", want, p.Where())
+	}
+
+	var m sync.Mutex
+	seen := make(map[string]int)
+	err = p.Fetch(500, func(resp *http.Response) error {
+		results := make([]map[string]string, 0)
+		if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+			return err
+		}
+		m.Lock()
+		defer m.Unlock()
+		for _, r := range results {
+			seen[r[":id"]]++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 2900 {
+		t.Errorf("Want %d records, have %d", 2900, len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("Record %s received %d times", id, n)
+		}
+	}
+}
+
+func TestRangeBounds(t *testing.T) {
+	bounds, err := rangeBounds("2014-01-01T00:00:00.000", "2014-01-05T00:00:00.000", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"'2014-01-02T00:00:00.000'", "'2014-01-03T00:00:00.000'", "'2014-01-04T00:00:00.000'"}
+	if !reflect.DeepEqual(bounds, want) {
+		t.Errorf("Want %v, have %v", want, bounds)
+	}
+
+	bounds, err = rangeBounds("5", "5", 4)
+	if err != nil || len(bounds) != 0 {
+		t.Errorf("Want no bounds, have %v (%v)", bounds, err)
+	}
+
+	if _, err := rangeBounds("a", "b", 2); err == nil {
+		t.Error("Wanted error for text values")
+	}
+}
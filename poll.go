@@ -0,0 +1,49 @@
+package soda
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	pollMinInterval = time.Second      //wait time before the first poll of a 202 Accepted response
+	pollMaxInterval = 30 * time.Second //maximum wait time between polls
+)
+
+// poll repeats req while Socrata responds with 202 Accepted, which means the result is still being prepared.
+// The wait time between requests is doubled every time up to pollMaxInterval, a Retry-After header is honoured.
+// Polling stops with an error when the context of req is done or its deadline passes before the next poll.
+func poll(r *GetRequest, req *http.Request, resp *http.Response, attempts *int) (*http.Response, error) {
+	ctx := req.Context()
+	interval := pollMinInterval
+
+	for polls := 0; resp.StatusCode == http.StatusAccepted; polls++ {
+		wait := interval
+		if ra, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			wait = ra
+		}
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return nil, fmt.Errorf("SODA request GET %s still processing after %d polls: %w", req.URL, polls, context.DeadlineExceeded)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if interval *= 2; interval > pollMaxInterval {
+			interval = pollMaxInterval
+		}
+
+		var err error
+		resp, err = r.send(req, attempts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,62 @@
+package soda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPollAccepted(t *testing.T) {
+
+	ds := newTestDataset(25)
+	processing := int32(2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&processing, -1) >= 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"message":"Processing"}`))
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	rows := make([]map[string]string, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 25 {
+		t.Errorf("Want %d rows, have %d", 25, len(rows))
+	}
+
+	atomic.StoreInt32(&processing, 1)
+	count, err := gr.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 25 {
+		t.Errorf("Want count %d, have %d", 25, count)
+	}
+
+	//the deadline passes before the result is ready
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = gr.GetContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Want %v, have %v", context.DeadlineExceeded, err)
+	}
+}
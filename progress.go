@@ -0,0 +1,214 @@
+package soda
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress describes the progress of a (multi page) fetch
+type Progress struct {
+	Pages     uint64        //Number of responses read completely
+	Records   uint64        //Number of records received (only counted for the json and csv formats)
+	Bytes     uint64        //Number of bytes received, after decompression
+	WireBytes uint64        //Number of bytes received over the network, less than Bytes for compressed responses
+	Total     uint64        //Expected number of records, 0 if unknown
+	Elapsed   time.Duration //Time since the start of the fetch
+}
+
+// RecordsPerSecond returns the average number of records received per second
+func (p Progress) RecordsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Records) / p.Elapsed.Seconds()
+}
+
+// BytesPerSecond returns the average number of bytes received per second
+func (p Progress) BytesPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// ETA returns the estimated remaining time, 0 if the total is unknown or nothing was received yet
+func (p Progress) ETA() time.Duration {
+	rate := p.RecordsPerSecond()
+	if p.Total == 0 || rate == 0 || p.Records >= p.Total {
+		return 0
+	}
+	return time.Duration(float64(p.Total-p.Records) / rate * float64(time.Second))
+}
+
+// ProgressFunc is called to report progress.
+// Calls are serialized, but it may be called from any goroutine reading a response body.
+type ProgressFunc func(Progress)
+
+// progressInterval is the minimum time between two progress reports while reading a response
+const progressInterval = 500 * time.Millisecond
+
+// progressTracker counts pages, records and bytes of responses and reports them to a ProgressFunc.
+// A nil progressTracker does nothing.
+type progressTracker struct {
+	pages   uint64 //first in struct for 64 bit alignment of atomic operations
+	records uint64
+	bytes   uint64
+	wire    uint64
+	total   uint64
+	start   time.Time
+	fn      ProgressFunc
+	m       sync.Mutex
+	last    time.Time
+}
+
+// newProgressTracker returns a new progressTracker, or nil if fn is nil
+func newProgressTracker(fn ProgressFunc, total uint64) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, total: total, start: time.Now()}
+}
+
+// wrap replaces the body of resp to count the bytes and records read
+func (t *progressTracker) wrap(resp *http.Response, format string) {
+	if t == nil {
+		return
+	}
+	resp.Body = &progressBody{ReadCloser: resp.Body, t: t, rows: newRowCounter(format)}
+}
+
+// report calls the ProgressFunc, when force is false at most once per progressInterval
+func (t *progressTracker) report(force bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	now := time.Now()
+	if !force && now.Sub(t.last) < progressInterval {
+		return
+	}
+	t.last = now
+	t.fn(Progress{
+		Pages:     atomic.LoadUint64(&t.pages),
+		Records:   atomic.LoadUint64(&t.records),
+		Bytes:     atomic.LoadUint64(&t.bytes),
+		WireBytes: atomic.LoadUint64(&t.wire),
+		Total:     t.total,
+		Elapsed:   now.Sub(t.start),
+	})
+}
+
+// progressBody is a response body reporting progress
+type progressBody struct {
+	io.ReadCloser
+	t     *progressTracker
+	rows  *rowCounter
+	bytes uint64
+	wire  uint64
+	done  bool
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.bytes += uint64(n)
+		atomic.AddUint64(&b.t.bytes, uint64(n))
+		atomic.AddUint64(&b.t.records, b.rows.count(p[:n]))
+	}
+	if wire := wireBytes(b.ReadCloser, b.bytes); wire > b.wire {
+		atomic.AddUint64(&b.t.wire, wire-b.wire)
+		b.wire = wire
+	}
+	if err == io.EOF {
+		b.finish()
+	} else if n > 0 {
+		b.t.report(false)
+	}
+	return n, err
+}
+
+func (b *progressBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+// finish counts the page and reports progress, only once
+func (b *progressBody) finish() {
+	if b.done {
+		return
+	}
+	b.done = true
+	atomic.AddUint64(&b.t.records, b.rows.end())
+	atomic.AddUint64(&b.t.pages, 1)
+	b.t.report(true)
+}
+
+// rowCounter counts records in a JSON array of objects or in CSV data while it is streamed
+type rowCounter struct {
+	format  string
+	depth   int
+	quoted  bool //inside a JSON string or quoted CSV field
+	escape  bool
+	header  bool //CSV: header was read
+	pending bool //CSV: data after the last newline
+}
+
+func newRowCounter(format string) *rowCounter {
+	if format == "" {
+		format = "json"
+	}
+	return &rowCounter{format: format}
+}
+
+// count returns the number of records completed in p
+func (rc *rowCounter) count(p []byte) uint64 {
+	n := uint64(0)
+	switch rc.format {
+	case "json":
+		for _, b := range p {
+			switch {
+			case rc.escape:
+				rc.escape = false
+			case rc.quoted && b == '\\':
+				rc.escape = true
+			case b == '"':
+				rc.quoted = !rc.quoted
+			case rc.quoted:
+			case b == '{' || b == '[':
+				if b == '{' && rc.depth == 1 {
+					n++
+				}
+				rc.depth++
+			case b == '}' || b == ']':
+				rc.depth--
+			}
+		}
+	case "csv":
+		for _, b := range p {
+			switch {
+			case b == '"':
+				rc.quoted = !rc.quoted
+				rc.pending = true
+			case b == '\n' && !rc.quoted:
+				if rc.header {
+					n++
+				}
+				rc.header = true
+				rc.pending = false
+			case b != '\r':
+				rc.pending = true
+			}
+		}
+	}
+	return n
+}
+
+// end returns the number of records completed by the end of the data
+func (rc *rowCounter) end() uint64 {
+	if rc.format == "csv" && rc.pending && rc.header {
+		rc.pending = false
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package soda
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressFetchAll(t *testing.T) {
+
+	ds := newTestDataset(2500)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	var m sync.Mutex
+	last := Progress{}
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+	gr.Progress = func(p Progress) {
+		m.Lock()
+		defer m.Unlock()
+		if p.Records < last.Records {
+			t.Errorf("Records decreased from %d to %d", last.Records, p.Records)
+		}
+		last = p
+	}
+
+	err := FetchAll(context.Background(), gr, 2, 1000, func(resp *http.Response) error {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if last.Pages != 3 || last.Records != 2500 || last.Total != 2500 || last.Bytes == 0 {
+		t.Errorf("Unexpected progress %+v", last)
+	}
+	if last.ETA() != 0 {
+		t.Errorf("Want no ETA when done, have %s", last.ETA())
+	}
+}
+
+func TestProgressGet(t *testing.T) {
+
+	ds := newTestDataset(10)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	reports := 0
+	last := Progress{}
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Format = "csv"
+	gr.Progress = func(p Progress) {
+		reports++
+		last = p
+	}
+
+	if _, err := gr.Count(); err != nil {
+		t.Fatal(err)
+	}
+	if reports != 0 {
+		t.Errorf("Want no progress reports for Count, have %d", reports)
+	}
+
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if last.Pages != 1 || last.Records != 10 {
+		t.Errorf("Unexpected progress %+v", last)
+	}
+}
+
+func TestRowCounter(t *testing.T) {
+	rc := newRowCounter("json")
+	data := `[{"a":"{\"x\":[1]}","b":{"c":[{"d":1}]}}, {"a":"}"} ,{}]`
+	n := uint64(0)
+	for i := 0; i < len(data); i += 3 { //split in chunks
+		end := i + 3
+		if end > len(data) {
+			end = len(data)
+		}
+		n += rc.count([]byte(data[i:end]))
+	}
+	if n != 3 {
+		t.Errorf("Want %d JSON records, have %d", 3, n)
+	}
+
+	rc = newRowCounter("csv")
+	n = rc.count([]byte("a,b\n\"1\n2\",3\n4,5")) + rc.end()
+	if n != 2 {
+		t.Errorf("Want %d CSV records, have %d", 2, n)
+	}
+
+	p := Progress{Records: 100, Total: 300, Elapsed: 10 * time.Second}
+	if p.RecordsPerSecond() != 10 || p.ETA() != 20*time.Second {
+		t.Errorf("Unexpected rate %f or ETA %s", p.RecordsPerSecond(), p.ETA())
+	}
+}
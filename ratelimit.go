@@ -0,0 +1,72 @@
+package soda
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the number of requests per second.
+// Socrata throttles requests per app token and per IP address, use the same RateLimiter
+// for all GetRequests using the same app token so their requests (including the requests of
+// OffsetGetRequest workers, retries and metadata requests) are limited together.
+// It is safe for use by multiple goroutines.
+type RateLimiter struct {
+	rate   float64 //tokens per second
+	burst  float64
+	m      sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing rate requests per second on average and
+// at most burst requests at once. A burst smaller than 1 is set to 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+// Waiting requests are allowed in the order in which Wait was called.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.m.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	//reserve a token, waiting until it is available when the bucket is empty
+	l.tokens--
+	wait := time.Duration(0)
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.m.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	if err := sleep(ctx, wait); err != nil {
+		//return the reserved token
+		l.m.Lock()
+		l.tokens++
+		l.m.Unlock()
+		return err
+	}
+	return nil
+}
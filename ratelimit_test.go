@@ -0,0 +1,60 @@
+package soda
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	l := NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//2 requests are allowed immediately, 5 more take 50ms at 100 per second
+	if d := time.Since(start); d < 45*time.Millisecond || d > time.Second {
+		t.Errorf("Want about 50ms, have %s", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	l = NewRateLimiter(1, 1)
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Want %v, have %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRateLimiterShared(t *testing.T) {
+
+	ds := newTestDataset(500)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Query.AddOrder(":id", DirAsc)
+	gr.RateLimiter = NewRateLimiter(100, 1)
+
+	//1 count request and 5 pages requested by 3 workers
+	start := time.Now()
+	err := FetchAll(context.Background(), gr, 3, 100, func(resp *http.Response) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Errorf("Want at least 50ms, have %s", d)
+	}
+	if ds.requests != 6 {
+		t.Errorf("Want %d requests, have %d", 6, ds.requests)
+	}
+}
@@ -0,0 +1,364 @@
+package soda
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Schema describes the columns of a SODA result in order.
+// Fields contains the field names and Types the Socrata data type of each field (when known).
+type Schema struct {
+	Fields []string
+	Types  []string
+}
+
+// ResponseSchema reads the schema from the X-SODA2-Fields and X-SODA2-Types headers of a JSON response.
+// Because Socrata omits null values from JSON rows this is the only way to know which columns were selected.
+func ResponseSchema(resp *http.Response) (*Schema, error) {
+	fh := resp.Header.Get("X-SODA2-Fields")
+	if fh == "" {
+		return nil, errors.New("cannot get schema, X-SODA2-Fields not present in HTTP header")
+	}
+	s := new(Schema)
+	if err := json.Unmarshal([]byte(fh), &s.Fields); err != nil {
+		return nil, fmt.Errorf("cannot parse X-SODA2-Fields header: %v", err)
+	}
+	if th := resp.Header.Get("X-SODA2-Types"); th != "" {
+		if err := json.Unmarshal([]byte(th), &s.Types); err != nil {
+			return nil, fmt.Errorf("cannot parse X-SODA2-Types header: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// ColumnsSchema creates a Schema from the metadata columns, for example Metadata.Columns
+func ColumnsSchema(cols []Column) *Schema {
+	s := &Schema{
+		Fields: make([]string, len(cols)),
+		Types:  make([]string, len(cols)),
+	}
+	for i, col := range cols {
+		s.Fields[i] = col.FieldName
+		s.Types[i] = col.DataTypeName
+	}
+	return s
+}
+
+// Index returns the position of field in the schema or -1 if it is not present
+func (s *Schema) Index(field string) int {
+	for i, f := range s.Fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// Type returns the Socrata data type of field, or an empty string if unknown
+func (s *Schema) Type(field string) string {
+	i := s.Index(field)
+	if i < 0 || i >= len(s.Types) {
+		return ""
+	}
+	return s.Types[i]
+}
+
+// ValueState describes the state of a column value in a Record
+type ValueState int
+
+const (
+	// StateMissing is used for columns that are not part of the schema (not selected or non-existent)
+	StateMissing ValueState = iota
+
+	// StateNull is used for columns in the schema without a value
+	StateNull
+
+	// StateEmpty is used for columns containing an empty string
+	StateEmpty
+
+	// StatePresent is used for columns containing a value
+	StatePresent
+)
+
+var (
+	// ErrNoColumn is returned by the Record getters when the column is not part of the schema
+	ErrNoColumn = errors.New("column not in schema")
+
+	// ErrNull is returned by the Record getters when the column value is null
+	ErrNull = errors.New("value is null")
+)
+
+// Record is a single row of a JSON result backed by a Schema.
+// Columns that are part of the schema but absent in the row are reported as null.
+type Record struct {
+	schema *Schema
+	values map[string]json.RawMessage
+}
+
+// NewRecord creates a Record from the raw JSON values of a row
+func NewRecord(schema *Schema, values map[string]json.RawMessage) Record {
+	if values == nil {
+		values = make(map[string]json.RawMessage)
+	}
+	return Record{schema: schema, values: values}
+}
+
+// Schema returns the schema of the record
+func (r Record) Schema() *Schema {
+	return r.schema
+}
+
+// State returns the ValueState of column
+func (r Record) State(column string) ValueState {
+	if r.schema == nil || r.schema.Index(column) < 0 {
+		return StateMissing
+	}
+	raw, ok := r.values[column]
+	if !ok || isNull(raw) {
+		return StateNull
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte(`""`)) {
+		return StateEmpty
+	}
+	return StatePresent
+}
+
+// Has returns if column is part of the schema
+func (r Record) Has(column string) bool {
+	return r.State(column) != StateMissing
+}
+
+// IsNull returns if column is part of the schema but has no value
+func (r Record) IsNull(column string) bool {
+	return r.State(column) == StateNull
+}
+
+// Raw returns the raw JSON value of column, null values are returned as JSON null
+func (r Record) Raw(column string) (json.RawMessage, error) {
+	switch r.State(column) {
+	case StateMissing:
+		return nil, fmt.Errorf("%w: %s", ErrNoColumn, column)
+	case StateNull:
+		return json.RawMessage("null"), nil
+	}
+	return r.values[column], nil
+}
+
+// String returns the value of column as string, non-string values are returned as raw JSON
+func (r Record) String(column string) (string, error) {
+	raw, err := r.value(column)
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, nil
+	}
+	return string(raw), nil
+}
+
+// Float returns the value of column as float64.
+// Socrata encodes numbers as JSON strings, both strings and numbers are accepted.
+func (r Record) Float(column string) (float64, error) {
+	s, err := r.String(column)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Time returns the value of column as time.Time.
+// Floating timestamps (without a timezone) are returned in UTC.
+func (r Record) Time(column string) (time.Time, error) {
+	s, err := r.String(column)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseTime(s)
+}
+
+// Point returns the value of column as a Point.
+// Both GeoJSON points and the legacy location type are supported.
+func (r Record) Point(column string) (Point, error) {
+	raw, err := r.value(column)
+	if err != nil {
+		return Point{}, err
+	}
+	return parsePoint(raw)
+}
+
+// Map returns all schema columns and their values, null values are returned as nil
+func (r Record) Map() map[string]interface{} {
+	m := make(map[string]interface{})
+	if r.schema == nil {
+		return m
+	}
+	for _, f := range r.schema.Fields {
+		raw, ok := r.values[f]
+		if !ok || isNull(raw) {
+			m[f] = nil
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			v = string(raw)
+		}
+		m[f] = v
+	}
+	return m
+}
+
+// MarshalJSON writes all schema columns in order, absent columns are written as explicit nulls
+func (r Record) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	if r.schema != nil {
+		for i, f := range r.schema.Fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(f)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			raw, ok := r.values[f]
+			if !ok || isNull(raw) {
+				buf.WriteString("null")
+				continue
+			}
+			buf.Write(raw)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r Record) value(column string) (json.RawMessage, error) {
+	switch r.State(column) {
+	case StateMissing:
+		return nil, fmt.Errorf("%w: %s", ErrNoColumn, column)
+	case StateNull:
+		return nil, fmt.Errorf("%w: %s", ErrNull, column)
+	}
+	return r.values[column], nil
+}
+
+// RecordDecoder reads Records one by one from a JSON array
+type RecordDecoder struct {
+	dec     *json.Decoder
+	schema  *Schema
+	started bool
+}
+
+// NewRecordDecoder creates a RecordDecoder reading from r using schema
+func NewRecordDecoder(r io.Reader, schema *Schema) *RecordDecoder {
+	return &RecordDecoder{dec: json.NewDecoder(r), schema: schema}
+}
+
+// Next returns the next Record, io.EOF is returned at the end of the array
+func (d *RecordDecoder) Next() (Record, error) {
+	if !d.started {
+		t, err := d.dec.Token()
+		if err != nil {
+			return Record{}, err
+		}
+		if delim, ok := t.(json.Delim); !ok || delim != '[' {
+			return Record{}, errors.New("expected a JSON array")
+		}
+		d.started = true
+	}
+	if !d.dec.More() {
+		return Record{}, io.EOF
+	}
+	values := make(map[string]json.RawMessage)
+	if err := d.dec.Decode(&values); err != nil {
+		return Record{}, err
+	}
+	return NewRecord(d.schema, values), nil
+}
+
+// DecodeRecords reads all Records from a JSON response.
+// If schema is nil the schema is read from the response headers.
+func DecodeRecords(resp *http.Response, schema *Schema) ([]Record, error) {
+	if schema == nil {
+		var err error
+		schema, err = ResponseSchema(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dec := NewRecordDecoder(resp.Body, schema)
+	records := make([]Record, 0)
+	for {
+		rec, err := dec.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// Point is a geographical point
+type Point struct {
+	Longitude float64
+	Latitude  float64
+}
+
+func parsePoint(raw json.RawMessage) (Point, error) {
+	v := struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+		Latitude    string    `json:"latitude"`
+		Longitude   string    `json:"longitude"`
+	}{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return Point{}, err
+	}
+	if v.Type == "Point" && len(v.Coordinates) == 2 {
+		return Point{Longitude: v.Coordinates[0], Latitude: v.Coordinates[1]}, nil
+	}
+	if v.Latitude == "" || v.Longitude == "" {
+		return Point{}, fmt.Errorf("cannot parse %s as point", raw)
+	}
+	lat, err := strconv.ParseFloat(v.Latitude, 64)
+	if err != nil {
+		return Point{}, err
+	}
+	lon, err := strconv.ParseFloat(v.Longitude, 64)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{Longitude: lon, Latitude: lat}, nil
+}
+
+// timeLayouts are the layouts used by Socrata for floating, fixed and calendar date timestamps
+var timeLayouts = []string{
+	floatingTimestamp,
+	"2006-01-02T15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time", s)
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
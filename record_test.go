@@ -0,0 +1,97 @@
+package soda
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecodeRecords(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-SODA2-Fields", `["farm_name","item","zipcode","planted","location_1"]`)
+		w.Header().Set("X-SODA2-Types", `["text","text","number","floating_timestamp","location"]`)
+		w.Write([]byte(`[
+			{"farm_name":"Bell Nurseries","item":"","zipcode":"06037","planted":"2014-09-04T15:01:44.000",
+			 "location_1":{"latitude":"41.6","longitude":"-72.8"}},
+			{"farm_name":"Beaver Brook Farm","location_1":{"type":"Point","coordinates":[-72.1,41.3]}}
+		]`))
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	records, err := DecodeRecords(resp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Want %d records, have %d", 2, len(records))
+	}
+
+	r := records[0]
+	states := map[string]ValueState{
+		"farm_name": StatePresent,
+		"item":      StateEmpty,
+		"category":  StateMissing,
+	}
+	for col, want := range states {
+		if r.State(col) != want {
+			t.Errorf("Want state %d for %s, have %d", want, col, r.State(col))
+		}
+	}
+	if f, err := r.Float("zipcode"); err != nil || f != 6037 {
+		t.Errorf("Want zipcode %d, have %v (%v)", 6037, f, err)
+	}
+	want := time.Date(2014, 9, 4, 15, 1, 44, 0, time.UTC)
+	if tm, err := r.Time("planted"); err != nil || !tm.Equal(want) {
+		t.Errorf("Want planted %s, have %s (%v)", want, tm, err)
+	}
+	if p, err := r.Point("location_1"); err != nil || p.Latitude != 41.6 || p.Longitude != -72.8 {
+		t.Errorf("Unexpected point %v (%v)", p, err)
+	}
+
+	r = records[1]
+	if !r.IsNull("item") {
+		t.Errorf("Want item to be null")
+	}
+	if _, err := r.String("item"); !errors.Is(err, ErrNull) {
+		t.Errorf("Want ErrNull, have %v", err)
+	}
+	if _, err := r.String("category"); !errors.Is(err, ErrNoColumn) {
+		t.Errorf("Want ErrNoColumn, have %v", err)
+	}
+	if p, err := r.Point("location_1"); err != nil || p.Latitude != 41.3 || p.Longitude != -72.1 {
+		t.Errorf("Unexpected point %v (%v)", p, err)
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"farm_name":"Beaver Brook Farm","item":null,"zipcode":null,"planted":null,"location_1":{"type":"Point","coordinates":[-72.1,41.3]}}`
+	if string(b) != wantJSON {
+		t.Errorf("Want %s, have %s", wantJSON, b)
+	}
+}
+
+func TestColumnsSchema(t *testing.T) {
+	s := ColumnsSchema([]Column{
+		{FieldName: "farm_name", DataTypeName: "text"},
+		{FieldName: "zipcode", DataTypeName: "number"},
+	})
+	if s.Index("zipcode") != 1 || s.Type("zipcode") != "number" {
+		t.Errorf("Unexpected schema %v", s)
+	}
+	if s.Index("item") != -1 || s.Type("item") != "" {
+		t.Errorf("Want item not to be present in %v", s)
+	}
+}
@@ -0,0 +1,203 @@
+package soda
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy describes if and when failed requests are retried.
+// Requests are retried on network errors and on the statuses 429, 500, 502, 503 and 504.
+// Only idempotent requests (GET, HEAD, OPTIONS, PUT and DELETE or requests with an Idempotency-Key header) are retried.
+type RetryPolicy struct {
+	MaxAttempts int                   //Maximum number of attempts, including the first one
+	MinBackoff  time.Duration         //Wait time before the first retry, doubled for every next retry. Default: 500ms
+	MaxBackoff  time.Duration         //Maximum wait time between attempts (a longer Retry-After is honoured). Default: 30s
+	Jitter      float64               //Fraction (0-1) of the wait time that is randomized to spread retries of multiple clients
+	OnAttempt   func(attempt Attempt) //Called after every attempt, for logging or metrics
+}
+
+// DefaultRetryPolicy returns a new policy retrying a request at most 3 times
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// jitterRand is seeded for every process, so clients started at the same time do not retry at the same moments
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns a random number in [0.0,1.0)
+func jitter() float64 {
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return jitterRand.Float64()
+}
+
+// Attempt describes a single attempt of a request, as passed to RetryPolicy.OnAttempt
+type Attempt struct {
+	Number     int           //Attempt number, starting at 1
+	URL        string        //Request URL
+	StatusCode int           //Response status, 0 when no response was received
+	Err        error         //Error when no response was received
+	Duration   time.Duration //Duration of the attempt
+	Retry      bool          //If the request will be retried
+	Wait       time.Duration //Wait time before the next attempt
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the wait time before attempt number+1
+func (p *RetryPolicy) backoff(number int, resp *http.Response) time.Duration {
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = 500 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	wait := min
+	for i := 1; i < number && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	if p.Jitter > 0 {
+		wait -= time.Duration(jitter() * p.Jitter * float64(wait))
+	}
+	if resp != nil {
+		if ra, ok := retryAfter(resp.Header.Get("Retry-After")); ok && ra > wait {
+			wait = ra
+		}
+	}
+	return wait
+}
+
+// retryAfter parses a Retry-After header, which contains seconds or a HTTP date
+func retryAfter(h string) (time.Duration, bool) {
+	if h == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(h); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryable returns if a response with status can be retried
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// idempotent returns if req can safely be sent more than once
+func idempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// send executes req using client and retries it according to policy, every attempt waits for limiter.
+// The response of the last attempt is returned, whatever its status. The number of attempts is added to attempts.
+func send(client *http.Client, policy *RetryPolicy, limiter *RateLimiter, req *http.Request, attempts *int) (*http.Response, error) {
+	ctx := req.Context()
+	maxAttempts := policy.maxAttempts()
+	if !idempotent(req) {
+		maxAttempts = 1
+	}
+
+	for number := 1; ; number++ {
+		if number > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := client.Do(req)
+		*attempts++
+		attempt := Attempt{
+			Number:   number,
+			URL:      req.URL.String(),
+			Err:      err,
+			Duration: time.Since(start),
+		}
+		if resp != nil {
+			attempt.StatusCode = resp.StatusCode
+		}
+
+		attempt.Retry = number < maxAttempts && ctx.Err() == nil && (err != nil || retryable(resp.StatusCode))
+		if attempt.Retry {
+			attempt.Wait = policy.backoff(number, resp)
+			//do not wait when the context will expire before the next attempt
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(attempt.Wait).After(deadline) {
+				attempt.Retry = false
+				attempt.Wait = 0
+			}
+		}
+		if policy != nil && policy.OnAttempt != nil {
+			policy.OnAttempt(attempt)
+		}
+		if !attempt.Retry {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, attempt.Wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,106 @@
+package soda
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+
+	ds := newTestDataset(10)
+	failures := int32(2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	attempts := make([]Attempt, 0)
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Retry = &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		OnAttempt: func(a Attempt) {
+			attempts = append(attempts, a)
+		},
+	}
+
+	count, err := gr.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("Want count %d, have %d", 10, count)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("Want %d attempts, have %d", 3, len(attempts))
+	}
+	for i, a := range attempts {
+		retry := i < 2
+		if a.Number != i+1 || a.Retry != retry {
+			t.Errorf("Want attempt %d retry %t, have %d %t", i+1, retry, a.Number, a.Retry)
+		}
+	}
+	if attempts[0].StatusCode != http.StatusServiceUnavailable || attempts[2].StatusCode != http.StatusOK {
+		t.Errorf("Want statuses 503 and 200, have %d and %d", attempts[0].StatusCode, attempts[2].StatusCode)
+	}
+
+	//out of attempts
+	attempts = attempts[:0]
+	atomic.StoreInt32(&failures, 3)
+	if _, err := gr.Count(); err == nil {
+		t.Error("Want error after the last attempt")
+	}
+	if len(attempts) != 3 {
+		t.Errorf("Want %d attempts, have %d", 3, len(attempts))
+	}
+
+	//Retry-After longer than the context deadline
+	attempts = attempts[:0]
+	atomic.StoreInt32(&failures, 1)
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := gr.CountContext(ctx); err == nil {
+		t.Error("Want error")
+	}
+	if time.Since(start) > time.Second || len(attempts) != 1 {
+		t.Errorf("Want a single attempt without waiting, have %d in %s", len(attempts), time.Since(start))
+	}
+
+	//not retryable
+	attempts = attempts[:0]
+	ts.Config.Handler = http.NotFoundHandler()
+	if _, err := gr.Metadata.Get(); err == nil {
+		t.Error("Want error")
+	}
+	if len(attempts) != 1 {
+		t.Errorf("Want %d attempt, have %d", 1, len(attempts))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	if d, ok := retryAfter("120"); !ok || d != 2*time.Minute {
+		t.Errorf("Want %s, have %s %t", 2*time.Minute, d, ok)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(date); !ok || d < 59*time.Minute || d > time.Hour {
+		t.Errorf("Want about %s, have %s %t", time.Hour, d, ok)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("Want invalid Retry-After")
+	}
+}
@@ -0,0 +1,64 @@
+//go:build go1.21
+// +build go1.21
+
+package soda
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+// SlogInterceptor returns an Interceptor logging every SODA call to logger when it is complete,
+// with the operation, dataset, query parameters (secrets redacted), status, duration, bytes and attempts.
+// Successful calls are logged at level Info and failed calls at level Error.
+func SlogInterceptor(logger *slog.Logger) Interceptor {
+	return func(call *Call, next Handler) (*http.Response, error) {
+		ctx := call.HTTPRequest.Context()
+		return observe(call, next, newSpan(call), func(span *Span) {
+			logSpan(ctx, logger, span)
+		})
+	}
+}
+
+func logSpan(ctx context.Context, logger *slog.Logger, span *Span) {
+	level := slog.LevelInfo
+	if span.Err != nil {
+		level = slog.LevelError
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	keys := make([]string, 0, len(span.Query))
+	for key := range span.Query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	query := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		vals := span.Query[key]
+		if len(vals) == 1 {
+			query = append(query, slog.String(key, vals[0]))
+		} else {
+			query = append(query, slog.Any(key, vals))
+		}
+	}
+
+	attrs := []slog.Attr{
+		slog.String("op", span.Op),
+		slog.String("dataset", span.Dataset),
+		slog.String("method", span.Method),
+		slog.String("url", span.URL),
+		slog.Group("query", query...),
+		slog.Int("status", span.StatusCode),
+		slog.Duration("duration", span.Duration),
+		slog.Int64("bytes", span.Bytes),
+		slog.Int("attempts", span.Attempts),
+	}
+	if span.Err != nil {
+		attrs = append(attrs, slog.String("error", span.Err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "soda "+span.Op, attrs...)
+}
@@ -0,0 +1,58 @@
+//go:build go1.21
+// +build go1.21
+
+package soda
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlogInterceptor(t *testing.T) {
+
+	ds := newTestDataset(10)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Interceptors = []Interceptor{SlogInterceptor(logger)}
+	gr.Query.Where = "n > 5"
+	gr.Filters["$$app_token"] = "secret"
+
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	entry := struct {
+		Level    string            `json:"level"`
+		Msg      string            `json:"msg"`
+		Op       string            `json:"op"`
+		Dataset  string            `json:"dataset"`
+		Query    map[string]string `json:"query"`
+		Status   int               `json:"status"`
+		Bytes    int               `json:"bytes"`
+		Attempts int               `json:"attempts"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Cannot read log %s: %v", buf, err)
+	}
+	if entry.Level != "INFO" || entry.Op != OpGet || entry.Dataset != "hma6-9xbg" || entry.Status != 200 {
+		t.Errorf("Unexpected log entry %s", buf)
+	}
+	if entry.Bytes != len(body) || entry.Attempts != 1 {
+		t.Errorf("Want %d bytes and 1 attempt, have %d and %d", len(body), entry.Bytes, entry.Attempts)
+	}
+	if entry.Query["$where"] != "n > 5" || entry.Query["$$app_token"] != "REDACTED" {
+		t.Errorf("Want redacted query, have %v", entry.Query)
+	}
+}
@@ -2,12 +2,13 @@
 package soda
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -20,33 +21,92 @@ import (
 // This is NOT safe for use by multiple goroutines as Format, Filters and Query will be overwritten.
 // Create a new GetRequest in each goroutine you use or use an OffsetGetRequest
 type GetRequest struct {
-	apptoken   string
-	endpoint   string //endpoint without format (not .json etc at the end)
-	Format     string //json, csv etc
-	Filters    SimpleFilters
-	Query      SoSQL
-	Metadata   metadata
-	HTTPClient *http.Client //For clients who need a custom HTTP client
+	apptoken           string
+	endpoint           string  //endpoint without format (not .json etc at the end)
+	client             *Client //Client that created the request, if any
+	Format             string  //json, csv etc
+	Filters            SimpleFilters
+	Query              SoSQL
+	Metadata           metadata
+	HTTPClient         *http.Client  //For clients who need a custom HTTP client
+	MaxLimit           uint          //Maximum number of records per request, larger limits are split in multiple requests. Default: DefaultMaxLimit
+	Progress           ProgressFunc  //Called to report progress of Get and of the paginators created from this request
+	Retry              *RetryPolicy  //Retry policy for failed requests, including metadata and export requests. Default: no retries
+	RateLimiter        *RateLimiter  //Limits the number of requests, share it between all requests using the same app token
+	DisableCompression bool          //Request uncompressed data (Accept-Encoding: identity)
+	Conditional        bool          //Remember the Validator of every Get response and use it for the next Get
+	Validator          *Validator    //Validator for a conditional Get, ErrNotModified is returned when the data did not change
+	Cache              Cache         //Cache for Get responses, see MemoryCache and DiskCache
+	NoCache            bool          //Bypass the cache, the response is not read from or stored in the Cache
+	CacheCheckModified bool          //Check if the dataset was modified after a cached response was stored before using it
+	Interceptors       []Interceptor //Called in order for every HTTP request, see Interceptor
 }
 
 // NewGetRequest creates a new GET request, the endpoint must be specified without the format.
 // For example https://data.ct.gov/resource/hma6-9xbg
 func NewGetRequest(endpoint, apptoken string) *GetRequest {
-	return &GetRequest{
+	r := &GetRequest{
 		apptoken: apptoken,
 		endpoint: endpoint,
 		Filters:  make(SimpleFilters),
 		Metadata: newMetadata(endpoint),
 	}
+	r.Metadata.gr = r
+	return r
 }
 
-// Get executes the HTTP GET request
+// Get executes the HTTP GET request.
+// If Query.Limit is larger than MaxLimit multiple requests are done and the results are combined.
+// If Progress is set, progress is reported while the response body is read.
+// If Conditional or Validator is set a conditional request is done (not for combined requests),
+// ErrNotModified is returned when the data did not change.
+// If Cache is set (and NoCache is not) a cached response is returned, or the response is stored in the cache
+// when its body is read to the end.
 func (r *GetRequest) Get() (*http.Response, error) {
+	return r.GetContext(context.Background())
+}
+
+// GetContext is like Get but uses ctx for the HTTP request(s)
+func (r *GetRequest) GetContext(ctx context.Context) (*http.Response, error) {
+	ctx = withOp(ctx, OpGet)
+	fetch := func() (*http.Response, error) {
+		if (r.Conditional || r.Validator != nil) && r.Query.Limit <= r.maxLimit() {
+			return r.getConditional(ctx)
+		}
+		return r.execute(ctx)
+	}
+	var resp *http.Response
+	var err error
+	if r.Cache != nil && !r.NoCache {
+		resp, err = r.getCached(ctx, fetch)
+	} else {
+		resp, err = fetch()
+	}
+	if err != nil {
+		return nil, err
+	}
+	newProgressTracker(r.Progress, 0).wrap(resp, r.Format)
+	return resp, nil
+}
+
+// execute executes the HTTP GET request without progress reporting
+func (r *GetRequest) execute(ctx context.Context) (*http.Response, error) {
+	if err := r.checkOffset(); err != nil {
+		return nil, err
+	}
+	if r.Query.Limit > r.maxLimit() {
+		return getSplit(ctx, r)
+	}
+	return get(ctx, r, r.URLValues().Encode())
+}
+
+// checkOffset returns an error if an offset is used without an order
+func (r *GetRequest) checkOffset() error {
 	//If offset is used we must specify an order
 	if r.Query.Offset > 0 && len(r.Query.Order) == 0 {
-		return nil, errors.New("cannot use an offset without setting the order")
+		return errors.New("cannot use an offset without setting the order")
 	}
-	return get(r, r.URLValues().Encode())
+	return nil
 }
 
 // GetEndpoint returns the complete SODA URL with format
@@ -69,26 +129,54 @@ func (r *GetRequest) URLValues() url.Values {
 	return uv
 }
 
+// clone returns a copy of r which can be modified without changing r
+func (r *GetRequest) clone() *GetRequest {
+	c := *r
+	c.Filters = make(SimpleFilters)
+	for key, val := range r.Filters {
+		c.Filters[key] = val
+	}
+	c.Query.Select = append([]string{}, r.Query.Select...)
+	c.Query.Order = append(c.Query.Order[:0:0], r.Query.Order...)
+	c.Metadata.gr = &c
+	return &c
+}
+
 // Count gets the total number of records in the dataset
 // by executing a SODA request
 func (r *GetRequest) Count() (uint, error) {
+	return r.CountContext(context.Background())
+}
+
+// CountContext is like Count but uses ctx for the HTTP request
+func (r *GetRequest) CountContext(ctx context.Context) (uint, error) {
+	count, _, err := r.count(ctx)
+	return count, err
+}
+
+// count returns the number of records and the headers of the count response
+func (r *GetRequest) count(ctx context.Context) (uint, http.Header, error) {
+	ctx = withOp(ctx, OpCount)
 
 	oldformat := r.Format
 	oldorder := r.Query.Order
+	oldlimit := r.Query.Limit
 	oldselect := r.Query.Select
 	defer func() {
 		r.Format = oldformat
 		r.Query.Order = oldorder
+		r.Query.Limit = oldlimit
 		r.Query.Select = oldselect
 	}()
 
 	r.Format = "json"
 	r.Query.Select = []string{"count(*)"}
+	r.Query.Limit = 0
 	r.Query.ClearOrder()
 
-	resp, err := r.Get()
+	resp, err := r.execute(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
@@ -97,21 +185,27 @@ func (r *GetRequest) Count() (uint, error) {
 	}, 0)
 	err = json.NewDecoder(resp.Body).Decode(&count)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	if len(count) == 0 {
-		return 0, errors.New("empty count response")
+		return 0, nil, errors.New("empty count response")
 	}
 	icount, err := strconv.Atoi(count[0].Count)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
-	return uint(icount), nil
+	return uint(icount), resp.Header, nil
 }
 
 // Fields returns all the fields present in the dataset (ignores select fields).
 // Spaces in fieldnames are replaced by underscores.
 func (r *GetRequest) Fields() ([]string, error) {
+	return r.FieldsContext(context.Background())
+}
+
+// FieldsContext is like Fields but uses ctx for the HTTP request
+func (r *GetRequest) FieldsContext(ctx context.Context) ([]string, error) {
+	ctx = withOp(ctx, OpFields)
 
 	oldformat := r.Format
 	oldorder := r.Query.Order
@@ -129,7 +223,7 @@ func (r *GetRequest) Fields() ([]string, error) {
 	r.Query.Limit = 0
 	r.Query.ClearOrder()
 
-	resp, err := r.Get()
+	resp, err := r.execute(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +243,12 @@ func (r *GetRequest) Fields() ([]string, error) {
 
 // Modified returns when the dataset was last updated
 func (r *GetRequest) Modified() (time.Time, error) {
+	return r.ModifiedContext(context.Background())
+}
+
+// ModifiedContext is like Modified but uses ctx for the HTTP request
+func (r *GetRequest) ModifiedContext(ctx context.Context) (time.Time, error) {
+	ctx = withOp(ctx, OpModified)
 
 	oldformat := r.Format
 	oldorder := r.Query.Order
@@ -166,15 +266,20 @@ func (r *GetRequest) Modified() (time.Time, error) {
 	r.Query.Limit = 0
 	r.Query.ClearOrder()
 
-	resp, err := r.Get()
+	resp, err := r.execute(ctx)
 	if err != nil {
 		return time.Time{}, err
 	}
 	defer resp.Body.Close()
 
-	lms := resp.Header.Get("X-Soda2-Truth-Last-Modified")
+	return lastModified(resp.Header)
+}
+
+// lastModified returns the last modified date of the dataset from the HTTP header
+func lastModified(h http.Header) (time.Time, error) {
+	lms := h.Get("X-Soda2-Truth-Last-Modified")
 	if lms == "" {
-		lms = resp.Header.Get("Last-Modified")
+		lms = h.Get("Last-Modified")
 	}
 	if lms == "" {
 		return time.Time{}, errors.New("cannot get last modified date, field not present in HTTP header")
@@ -208,7 +313,7 @@ type SoSQL struct {
 		Desc   bool   //Descending. Default: false = Ascending
 	} //Specifies the order of results. Default: Unspecified order, but it will be consistent across paging
 	Group  string //Column to group results on, similar to SQL Grouping. Default: No grouping
-	Limit  uint   //Maximum number of results to return. Default: 1000 (with a maximum of 50,000)
+	Limit  uint   //Maximum number of results to return. Default: 1000 (split in multiple requests above GetRequest.MaxLimit)
 	Offset uint   //Offset count into the results to start at, used for paging. Default: 0
 	Q      string //Performs a full text search for a value. Default: No search
 
@@ -279,86 +384,412 @@ func (sq *SoSQL) URLValues() url.Values {
 
 // OffsetGetRequest is a request getter that gets all the records using the filters and limits from gr and
 // is safe to use by multiple goroutines, use Next(number) to get the next number of records.
+// Pages that fail are kept and requested again by the next call to Next, so no records are lost.
+// The last modified date of the dataset is checked on every page, see OnChange.
 // A sync.WaitGroup is embedded for easy concurrency.
 type OffsetGetRequest struct {
-	gr     *GetRequest
-	m      sync.Mutex
-	offset uint
-	count  uint
+	OnChange ChangePolicy //What to do when the dataset changes during the fetch. Default: ChangeReport
+
+	gr        *GetRequest
+	m         sync.Mutex
+	offset    uint
+	count     uint
+	seq       uint
+	gen       uint
+	failed    []Page
+	progress  *progressTracker
+	unbounded bool //page until a short page is received, count is only a hint
+	end       uint //unbounded: offset after the last record, valid when endKnown is set
+	endKnown  bool
+	changeErr *ChangeError //set when the fetch failed with OnChange ChangeFail
+	changeTracker
 	sync.WaitGroup
 }
 
-// ErrDone is returned by OffsetGetRequest.Next when done
+// Page describes a range of records requested by an OffsetGetRequest
+type Page struct {
+	Offset  uint //Offset of the first record
+	Limit   uint //Number of records requested
+	Seq     uint //Sequence number, pages are numbered in offset order starting at 0
+	Attempt int  //Number of times the page has been requested, starting at 1
+
+	gen uint            //restart generation the page belongs to
+	ctx context.Context //context used to request the page
+}
+
+// Context returns the context the page was requested with.
+// For pages passed to a FetchAllPages handler it is cancelled when the other workers stop.
+func (p Page) Context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// PageError is returned by OffsetGetRequest.NextPage when a page fails.
+// The page is kept and will be requested again by the next call to Next or NextPage.
+type PageError struct {
+	Page Page
+	Err  error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("page %d (offset %d, limit %d) failed: %v", e.Page.Seq, e.Page.Offset, e.Page.Limit, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// ErrDone is returned by OffsetGetRequest.Next and KeysetGetRequest.Next when done
 var ErrDone = errors.New("Done")
 
 // Next gets the next number of records
 func (o *OffsetGetRequest) Next(number uint) (*http.Response, error) {
-	o.m.Lock() //lock to protect offset
-	if o.IsDone() {
+	return o.NextContext(context.Background(), number)
+}
+
+// NextContext is like Next but uses ctx for the HTTP request
+func (o *OffsetGetRequest) NextContext(ctx context.Context, number uint) (*http.Response, error) {
+	_, resp, err := o.NextPageContext(ctx, number)
+	return resp, err
+}
+
+// NextPage gets the next number of records and returns the Page describing them.
+// Failed pages are requested again before any new pages, using their original offset and limit.
+// When a page fails a *PageError is returned.
+func (o *OffsetGetRequest) NextPage(number uint) (Page, *http.Response, error) {
+	return o.NextPageContext(context.Background(), number)
+}
+
+// NextPageContext is like NextPage but uses ctx for the HTTP request
+func (o *OffsetGetRequest) NextPageContext(ctx context.Context, number uint) (Page, *http.Response, error) {
+	ctx = withOp(ctx, OpNext)
+	if len(o.gr.Query.Order) == 0 { //If offset is used we must specify an order
+		return Page{}, nil, errors.New("cannot use an offset without setting the order")
+	}
+
+	page, err := o.allocate(number)
+	if err != nil {
+		return Page{}, nil, err
+	}
+	page.ctx = ctx
+
+	r := o.gr.clone() //do not modify gr, it is shared by all workers
+	r.Query.Offset = page.Offset
+	r.Query.Limit = page.Limit
+	resp, err := get(ctx, r, r.URLValues().Encode())
+	if err != nil {
+		o.Requeue(page)
+		return page, nil, &PageError{Page: page, Err: err}
+	}
+	if err := o.checkChange(ctx, page, resp); err != nil {
+		resp.Body.Close()
+		return page, nil, err
+	}
+	if o.unbounded {
+		if err := o.checkEnd(page, resp, r.Format); err != nil {
+			o.Requeue(page)
+			return page, nil, &PageError{Page: page, Err: err}
+		}
+	}
+	o.progress.wrap(resp, r.Format)
+	return page, resp, nil
+}
+
+// checkEnd reads the page and marks the end of the records when the page is short
+func (o *OffsetGetRequest) checkEnd(page Page, resp *http.Response, format string) error {
+	body, err := bufferBody(resp)
+	if err != nil {
+		return err
+	}
+
+	rows, err := countRows(format, body)
+	if err != nil {
+		return err
+	}
+
+	o.m.Lock()
+	defer o.m.Unlock()
+	if rows < page.Limit && page.gen == o.gen {
+		end := page.Offset + rows
+		if !o.endKnown || end < o.end {
+			o.end = end
+			o.endKnown = true
+		}
+	}
+	return nil
+}
+
+// checkChange checks the last modified date of the page against the policy.
+// With ChangeFail the page is requeued and all next pages fail, with ChangeRestart paging starts again at offset 0.
+func (o *OffsetGetRequest) checkChange(ctx context.Context, page Page, resp *http.Response) error {
+	o.m.Lock()
+	if page.gen != o.gen {
+		//page was requested before a restart
 		o.m.Unlock()
-		return nil, ErrDone
+		modified := o.changeTracker.Modified()
+		return &ChangeError{Restarted: true, Previous: modified, Current: modified}
 	}
-	if len(o.gr.Query.Order) == 0 { //If offset is used we must specify an order
-		return nil, errors.New("cannot use an offset without setting the order")
+	cerr := o.observe(resp.Header, o.OnChange)
+	if cerr == nil {
+		o.m.Unlock()
+		return nil
+	}
+	if !cerr.Restarted {
+		page.ctx = nil
+		o.failed = append(o.failed, page)
+		if o.changeErr == nil {
+			o.changeErr = cerr
+		}
+		o.m.Unlock()
+		return cerr
+	}
+	o.endKnown = false
+	o.offset = 0
+	o.seq = 0
+	o.failed = nil
+	o.gen++
+	gen := o.gen
+	o.m.Unlock()
+	if o.unbounded {
+		return cerr
+	}
+
+	//count without holding the lock, pages allocated in the meantime use the previous count
+	count, _, err := o.gr.clone().count(ctx)
+	if err != nil {
+		return err
+	}
+	o.m.Lock()
+	defer o.m.Unlock()
+	if o.gen == gen {
+		o.count = count
 	}
-	if o.offset+number > o.count {
-		number = o.count - o.offset
+	return cerr
+}
+
+// allocate returns the next page to get, failed pages are returned first.
+// ErrDone is returned when all pages were allocated.
+func (o *OffsetGetRequest) allocate(number uint) (Page, error) {
+	o.m.Lock() //lock to protect offset
+	defer o.m.Unlock()
+
+	if o.changeErr != nil {
+		return Page{}, o.changeErr
+	}
+	if len(o.failed) > 0 {
+		page := o.failed[0]
+		o.failed = o.failed[1:]
+		page.Attempt++
+		return page, nil
+	}
+	if o.unbounded {
+		if o.endKnown && o.offset >= o.end {
+			return Page{}, ErrDone
+		}
+	} else {
+		if o.offset >= o.count {
+			return Page{}, ErrDone
+		}
+		if o.offset+number > o.count {
+			number = o.count - o.offset
+		}
 	}
-	o.gr.Query.Offset = o.offset
-	o.gr.Query.Limit = number
-	rawquery := o.gr.URLValues().Encode()
+	page := Page{Offset: o.offset, Limit: number, Seq: o.seq, Attempt: 1, gen: o.gen}
 	o.offset += number
-	o.m.Unlock() //unlock before the request is done
-	return get(o.gr, rawquery)
+	o.seq++
+	return page, nil
 }
 
-// Count returns the number of records from memory
+// Requeue hands a page back so it is requested again by the next call to Next or NextPage.
+// Use this when processing a page fails after it was received, for example when reading the response body fails.
+func (o *OffsetGetRequest) Requeue(page Page) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	if page.gen == o.gen {
+		page.ctx = nil
+		o.failed = append(o.failed, page)
+	}
+}
+
+// Failed returns the pages which failed and are waiting to be requested again
+func (o *OffsetGetRequest) Failed() []Page {
+	o.m.Lock()
+	defer o.m.Unlock()
+	return append([]Page{}, o.failed...)
+}
+
+// Count returns the number of records from memory.
+// For an unbounded OffsetGetRequest this is only a hint, 0 is returned when no count was done.
 func (o *OffsetGetRequest) Count() uint {
+	o.m.Lock()
+	defer o.m.Unlock()
 	return o.count
 }
 
-// IsDone returns if we have gotten all records
+// IsDone returns if we have gotten all records, including failed pages
 func (o *OffsetGetRequest) IsDone() bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+	if len(o.failed) > 0 || o.changeErr != nil {
+		return false
+	}
+	if o.unbounded {
+		return o.endKnown && o.offset >= o.end
+	}
 	return o.offset >= o.count
 }
 
 // NewOffsetGetRequest creates a new OffsetGetRequest from gr
 // and does a count request to determine the number of records to get
 func NewOffsetGetRequest(gr *GetRequest) (*OffsetGetRequest, error) {
-	count, err := gr.Count()
+	return NewOffsetGetRequestContext(context.Background(), gr)
+}
+
+// NewOffsetGetRequestContext is like NewOffsetGetRequest but uses ctx for the count request
+func NewOffsetGetRequestContext(ctx context.Context, gr *GetRequest) (*OffsetGetRequest, error) {
+	count, h, err := gr.count(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &OffsetGetRequest{gr: gr, offset: 0, count: count}, nil
+	o := &OffsetGetRequest{gr: gr, offset: 0, count: count, progress: newProgressTracker(gr.Progress, uint64(count))}
+	o.observe(h, ChangeReport) //changes are detected from the moment of the count
+	return o, nil
+}
+
+// NewUnboundedOffsetGetRequest creates a new OffsetGetRequest from gr which keeps getting pages
+// until a page with less records than requested is received, so records added during the fetch are not missed.
+// Use this for datasets where a count request is too slow or times out.
+// If countHint is true a count request is done, the result is only used as hint (see Count), for example for progress reporting.
+// Pages are read into memory to count the records, only the json, geojson and csv formats are supported.
+func NewUnboundedOffsetGetRequest(gr *GetRequest, countHint bool) (*OffsetGetRequest, error) {
+	return NewUnboundedOffsetGetRequestContext(context.Background(), gr, countHint)
+}
+
+// NewUnboundedOffsetGetRequestContext is like NewUnboundedOffsetGetRequest but uses ctx for the count request
+func NewUnboundedOffsetGetRequestContext(ctx context.Context, gr *GetRequest, countHint bool) (*OffsetGetRequest, error) {
+	o := &OffsetGetRequest{gr: gr, unbounded: true}
+	if countHint {
+		count, h, err := gr.count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.count = count
+		o.observe(h, ChangeReport) //changes are detected from the moment of the count
+	}
+	o.progress = newProgressTracker(gr.Progress, uint64(o.count))
+	return o, nil
+}
+
+// countRows returns the number of records in a response body
+func countRows(format string, body []byte) (uint, error) {
+	switch format {
+	case "json":
+		rows := make([]json.RawMessage, 0)
+		if err := json.Unmarshal(body, &rows); err != nil {
+			return 0, err
+		}
+		return uint(len(rows)), nil
+	case "geojson":
+		fc := struct {
+			Features []json.RawMessage `json:"features"`
+		}{}
+		if err := json.Unmarshal(body, &fc); err != nil {
+			return 0, err
+		}
+		return uint(len(fc.Features)), nil
+	case "csv":
+		records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if err != nil {
+			return 0, err
+		}
+		if len(records) == 0 {
+			return 0, nil
+		}
+		return uint(len(records) - 1), nil //header
+	}
+	return 0, fmt.Errorf("cannot count records using format %s", format)
 }
 
 // get is the function that executes the HTTP request
-func get(r *GetRequest, rawquery string) (*http.Response, error) {
+func get(ctx context.Context, r *GetRequest, rawquery string) (*http.Response, error) {
 
-	client := http.DefaultClient
-	if r.HTTPClient != nil {
-		client = r.HTTPClient
+	req, err := newRequest(ctx, r, rawquery)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", r.GetEndpoint(), nil)
+	return do(r, req)
+}
+
+// newRequest creates the HTTP request for r using rawquery
+func newRequest(ctx context.Context, r *GetRequest, rawquery string) (*http.Request, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", r.GetEndpoint(), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.URL.RawQuery = rawquery
+	if r.DisableCompression {
+		//an explicit header stops http.Transport from requesting gzip itself
+		req.Header.Set("Accept-Encoding", "identity")
+	} else {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return req, nil
+}
+
+// send executes req using the HTTP client, app token, retry policy and rate limiter of r
+// and the user agent and credentials of the Client that created r
+func (r *GetRequest) send(req *http.Request, attempts *int) (*http.Response, error) {
+
+	client := http.DefaultClient
+	if r.HTTPClient != nil {
+		client = r.HTTPClient
+	}
 	req.Header.Set("X-App-Token", r.apptoken)
+	if r.client != nil {
+		r.client.setHeaders(req)
+	}
+
+	return send(client, r.Retry, r.RateLimiter, req, attempts)
+}
+
+// do executes req through the Interceptors of r
+func do(r *GetRequest, req *http.Request) (*http.Response, error) {
+	call := &Call{Op: Operation(req.Context()), Request: r, HTTPRequest: req}
+	return intercept(r.Interceptors, call, func(call *Call) (*http.Response, error) {
+		return doRequest(r, call)
+	})
+}
+
+// doRequest executes the HTTP request of call using the HTTP client, app token, retry policy and rate limiter of r.
+// Error statuses are returned as *Error, except a 416 status for requests with a Range header which the caller must handle.
+// 202 Accepted responses are polled until the result is ready.
+func doRequest(r *GetRequest, call *Call) (*http.Response, error) {
+	req := call.HTTPRequest
 
 	// Execute
-	resp, err := client.Do(req)
+	resp, err := r.send(req, &call.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = poll(r, req, resp, &call.Attempts)
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeBody(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
 
 	if resp.StatusCode >= 400 {
-		errMsg, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && req.Header.Get("Range") != "" {
+			return resp, nil
 		}
-		return nil, fmt.Errorf("SODA error %d:\nURL: GET %s\nResponse: %s", resp.StatusCode, req.URL.String(), errMsg)
+		return nil, newError(req, resp)
 	}
 
 	return resp, nil
@@ -1,10 +1,18 @@
 package soda
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -278,14 +286,16 @@ func TestOffsetGetRequest(t *testing.T) {
 					break
 				}
 				if err != nil {
-					t.Fatal(err)
+					t.Error(err)
+					return
 				}
 
 				results := []Business{}
 				err = json.NewDecoder(resp.Body).Decode(&results)
 				resp.Body.Close()
 				if err != nil {
-					t.Fatal(err)
+					t.Error(err)
+					return
 				}
 				atomic.AddUint64(&records, uint64(len(results)))
 			}
@@ -300,6 +310,151 @@ func TestOffsetGetRequest(t *testing.T) {
 	t.Logf("Got %d records in %s using %d goroutines", records, time.Since(start), numGoroutines)
 }
 
+func TestOffsetGetRequestFailedPage(t *testing.T) {
+
+	ds := newTestDataset(2500)
+	failed := int32(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("$offset") == "1000" && atomic.AddInt32(&failed, 1) == 1 {
+			http.Error(w, "try again", http.StatusBadGateway)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	ogr, err := NewOffsetGetRequest(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ogr.NextPage(1000); err == nil {
+		t.Fatal("Wanted error for a request without order")
+	}
+	gr.Query.AddOrder(":id", DirAsc)
+
+	seen := make(map[string]bool)
+	seqs := make([]uint, 0)
+	for {
+		page, resp, err := ogr.NextPage(1000)
+		if err == ErrDone {
+			break
+		}
+		if err != nil {
+			perr, ok := err.(*PageError)
+			if !ok || perr.Page.Offset != 1000 {
+				t.Fatalf("Want PageError for offset %d, have %v", 1000, err)
+			}
+			if len(ogr.Failed()) != 1 || ogr.IsDone() {
+				t.Errorf("Want failed page to be kept, have %v", ogr.Failed())
+			}
+			continue
+		}
+		results := make([]map[string]string, 0)
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			seen[r[":id"]] = true
+		}
+		if page.Seq == 1 && page.Attempt != 2 {
+			t.Errorf("Want attempt %d for page %d, have %d", 2, page.Seq, page.Attempt)
+		}
+		seqs = append(seqs, page.Seq)
+	}
+
+	if len(seen) != 2500 {
+		t.Errorf("Want %d records, have %d", 2500, len(seen))
+	}
+	want := []uint{0, 1, 2}
+	if fmt.Sprint(seqs) != fmt.Sprint(want) {
+		t.Errorf("Want page sequence %v, have %v", want, seqs)
+	}
+}
+
+func TestUnboundedOffsetGetRequest(t *testing.T) {
+
+	ds := newTestDataset(2500)
+	ts := httptest.NewServer(ds)
+	defer ts.Close()
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.Format = "csv"
+	gr.Query.AddOrder(":id", DirAsc)
+	ogr, err := NewUnboundedOffsetGetRequest(gr, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ogr.Count() != 2500 {
+		t.Errorf("Want count hint %d, have %d", 2500, ogr.Count())
+	}
+
+	//records added after the count must not be missed
+	ds.rows = append(ds.rows, newTestDataset(2800).rows[2500:]...)
+
+	records := uint64(0)
+	err = ogr.FetchAll(context.Background(), 3, 1000, func(resp *http.Response) error {
+		rows, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			return err
+		}
+		atomic.AddUint64(&records, uint64(len(rows)-1))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records != 2800 {
+		t.Errorf("Want %d records, have %d", 2800, records)
+	}
+	if !ogr.IsDone() {
+		t.Error("Want OffsetGetRequest to be done")
+	}
+
+	ogr, err = NewUnboundedOffsetGetRequest(gr, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ogr.Count() != 0 || ogr.IsDone() {
+		t.Errorf("Want no count and not done, have %d, %t", ogr.Count(), ogr.IsDone())
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := gr.GetContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Want %v, have %v", context.DeadlineExceeded, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := gr.CountContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Want %v, have %v", context.Canceled, err)
+	}
+	if _, err := NewOffsetGetRequestContext(ctx, gr); !errors.Is(err, context.Canceled) {
+		t.Errorf("Want %v, have %v", context.Canceled, err)
+	}
+	if _, err := gr.Metadata.GetContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Want %v, have %v", context.Canceled, err)
+	}
+}
+
 type Business struct {
 	Business  string `json:"business"`
 	Category  string `json:"category"`
@@ -316,3 +471,137 @@ type Business struct {
 	Phone1  string `json:"phone1"`
 	Zipcode string `json:"zipcode"`
 }
+
+// testDataset is a fake SODA dataset used for tests that do not need the real webservice.
+// Rows have an :id, a numeric column n and a text column name, sorted on both :id and n.
+// Simple $where clauses (comparisons combined using AND), $limit, $offset and min/max/count selects are supported.
+type testDataset struct {
+	rows     []map[string]string
+	modified string
+	requests int32
+}
+
+func newTestDataset(n int) *testDataset {
+	ds := &testDataset{modified: "Thu, 04 Sep 2014 15:01:44 GMT"}
+	for i := 0; i < n; i++ {
+		ds.rows = append(ds.rows, map[string]string{
+			":id":  fmt.Sprintf("row-%05d", i),
+			"n":    strconv.Itoa(i),
+			"name": fmt.Sprintf("Farm %d", i),
+		})
+	}
+	return ds
+}
+
+var testCondition = regexp.MustCompile(`^(\S+) (>=|<=|>|<|=) (.+)$`)
+
+func (ds *testDataset) match(row map[string]string, where string) bool {
+	if where == "" {
+		return true
+	}
+	for _, cond := range strings.Split(where, " AND ") {
+		cond = strings.Trim(cond, "()")
+		if strings.HasSuffix(cond, " IS NOT NULL") {
+			if _, ok := row[strings.TrimSuffix(cond, " IS NOT NULL")]; !ok {
+				return false
+			}
+			continue
+		}
+		if strings.HasSuffix(cond, " IS NULL") {
+			if _, ok := row[strings.TrimSuffix(cond, " IS NULL")]; ok {
+				return false
+			}
+			continue
+		}
+		m := testCondition.FindStringSubmatch(cond)
+		if m == nil {
+			return false
+		}
+		val, ok := row[m[1]]
+		if !ok {
+			return false
+		}
+		c := 0
+		if strings.HasPrefix(m[3], "'") {
+			c = strings.Compare(val, strings.Trim(m[3], "'"))
+		} else {
+			a, _ := strconv.ParseFloat(val, 64)
+			b, _ := strconv.ParseFloat(m[3], 64)
+			if a < b {
+				c = -1
+			} else if a > b {
+				c = 1
+			}
+		}
+		switch m[2] {
+		case ">=":
+			ok = c >= 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case "<":
+			ok = c < 0
+		case "=":
+			ok = c == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (ds *testDataset) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&ds.requests, 1)
+	q := r.URL.Query()
+
+	rows := make([]map[string]string, 0)
+	for _, row := range ds.rows {
+		if ds.match(row, q.Get("$where")) {
+			rows = append(rows, row)
+		}
+	}
+
+	if ds.modified != "" {
+		w.Header().Set("Last-Modified", ds.modified)
+	}
+	sel := q.Get("$select")
+	switch {
+	case sel == "count(*)":
+		json.NewEncoder(w).Encode([]map[string]string{{"count": strconv.Itoa(len(rows))}})
+		return
+	case strings.HasPrefix(sel, "min("):
+		res := []map[string]string{{}}
+		if len(rows) > 0 {
+			res[0]["min"] = rows[0]["n"]
+			res[0]["max"] = rows[len(rows)-1]["n"]
+		}
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	offset, _ := strconv.Atoi(q.Get("$offset"))
+	limit := 1000
+	if l := q.Get("$limit"); l != "" {
+		limit, _ = strconv.Atoi(l)
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	if strings.HasSuffix(r.URL.Path, ".csv") {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{":id", "n", "name"})
+		for _, row := range rows {
+			cw.Write([]string{row[":id"], row["n"], row["name"]})
+		}
+		cw.Flush()
+		return
+	}
+	json.NewEncoder(w).Encode(rows)
+}
@@ -0,0 +1,471 @@
+package soda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Struct fields are mapped to SODA columns using the soda struct tag, for example:
+//
+//	type Farm struct {
+//		Name    string  `soda:"farm_name"`
+//		Zipcode int     `soda:"zipcode"`
+//		Items   int     `soda:"items,expr=count(item)"`
+//		Ignored string  `soda:"-"`
+//	}
+//
+// The expr option declares a computed column which is selected as "expr AS name",
+// it must be the last option in the tag because the expression may contain commas.
+// Fields without a soda tag use the name from their json tag, fields without either are ignored.
+
+// structField describes one struct field mapped to a SODA column
+type structField struct {
+	index  []int
+	column string
+	expr   string
+}
+
+// selectItem returns the $select expression for the field
+func (sf structField) selectItem() string {
+	if sf.expr == "" {
+		return sf.column
+	}
+	return fmt.Sprintf("%s AS %s", sf.expr, sf.column)
+}
+
+var structFieldCache sync.Map // map[reflect.Type][]structField
+
+// structFields returns the SODA fields for struct type t
+func structFields(t reflect.Type) []structField {
+	if f, ok := structFieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+	fields := collectStructFields(t, nil)
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+func collectStructFields(t reflect.Type, index []int) []structField {
+	fields := make([]structField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+
+		tag, hasTag := f.Tag.Lookup("soda")
+		if !hasTag {
+			if jt, ok := f.Tag.Lookup("json"); ok {
+				tag = strings.Split(jt, ",")[0]
+				hasTag = tag != ""
+			}
+		}
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && !hasTag {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, collectStructFields(ft, idx)...)
+			}
+			continue
+		}
+		if !hasTag || f.PkgPath != "" {
+			continue
+		}
+
+		sf := structField{index: idx}
+		if pos := strings.Index(tag, ",expr="); pos >= 0 {
+			sf.expr = tag[pos+len(",expr="):]
+			tag = tag[:pos]
+		}
+		sf.column = tag
+		if sf.column == "" {
+			continue
+		}
+		fields = append(fields, sf)
+	}
+	return fields
+}
+
+// structType returns the struct type for v, which can be a struct, a slice of structs or pointers to those
+func structType(v interface{}) (reflect.Type, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot use %T, a struct type is required", v)
+	}
+	return t, nil
+}
+
+// StructSelect returns the $select list for the struct type of v, using the soda struct tags.
+// v can be a struct, a pointer to a struct or a slice of those.
+func StructSelect(v interface{}) ([]string, error) {
+	t, err := structType(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := structFields(t)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("struct %s has no soda fields", t)
+	}
+	sel := make([]string, len(fields))
+	for i, f := range fields {
+		sel[i] = f.selectItem()
+	}
+	return sel, nil
+}
+
+// NewGetRequestFor creates a new GET request with Query.Select set from the soda struct tags of v,
+// so only the columns needed to decode into v are requested. Use DecodeJSON to decode the result.
+func NewGetRequestFor(endpoint, apptoken string, v interface{}) (*GetRequest, error) {
+	sel, err := StructSelect(v)
+	if err != nil {
+		return nil, err
+	}
+	gr := NewGetRequest(endpoint, apptoken)
+	gr.Format = "json"
+	gr.Query.Select = sel
+	return gr, nil
+}
+
+// DecodeJSON decodes a JSON result into v, which must be a pointer to a slice of structs (or struct pointers).
+// Fields are matched using the soda struct tags. Numbers, booleans, timestamps and points encoded as
+// JSON strings by Socrata are converted to the type of the field.
+func DecodeJSON(r io.Reader, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("cannot decode into %T, a pointer to a slice is required", v)
+	}
+	slice := rv.Elem()
+	et := slice.Type().Elem()
+	isPtr := et.Kind() == reflect.Ptr
+	if isPtr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode into %T, a slice of structs is required", v)
+	}
+	fields := structFields(et)
+
+	rows := make([]map[string]json.RawMessage, 0)
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		elem := reflect.New(et).Elem()
+		for _, f := range fields {
+			raw, ok := row[f.column]
+			if !ok || isNull(raw) {
+				continue
+			}
+			fv, err := fieldByIndex(elem, f.index)
+			if err != nil {
+				return err
+			}
+			if err := setValue(fv, raw); err != nil {
+				return fmt.Errorf("cannot decode column %s: %v", f.column, err)
+			}
+		}
+		if isPtr {
+			elem = elem.Addr()
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return nil
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but allocates nil embedded struct pointers.
+// Like encoding/json it returns an error for a pointer to an unexported struct, which cannot be allocated.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	pointType = reflect.TypeOf(Point{})
+)
+
+// setValue sets fv from a raw JSON SODA value
+func setValue(fv reflect.Value, raw json.RawMessage) error {
+	if fv.Kind() == reflect.Ptr {
+		v := reflect.New(fv.Type().Elem())
+		if err := setValue(v.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(v)
+		return nil
+	}
+
+	switch fv.Type() {
+	case timeType:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		t, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case pointType:
+		p, err := parsePoint(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(p))
+		return nil
+	}
+
+	if _, ok := fv.Addr().Interface().(json.Unmarshaler); ok {
+		return json.Unmarshal(raw, fv.Addr().Interface())
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetString(s)
+	case reflect.Bool:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		s, err := rawString(raw)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return json.Unmarshal(raw, fv.Addr().Interface())
+	}
+	return nil
+}
+
+// rawString returns a JSON string value unquoted, other values are returned as is
+func rawString(raw json.RawMessage) (string, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+	if len(raw) > 0 && (raw[0] == '{' || raw[0] == '[') {
+		return "", errors.New("cannot use a JSON object or array as a scalar value")
+	}
+	return string(raw), nil
+}
+
+// exampleValue is a column and value taken from a struct used as query example
+type exampleValue struct {
+	column string
+	value  reflect.Value
+}
+
+// exampleValues returns the non-zero soda fields of v, nil pointers are ignored but
+// non-nil pointers are always included so optional fields can be matched against zero values.
+// Computed (expr) fields cannot be filtered on and are ignored.
+func exampleValues(v interface{}) ([]exampleValue, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("cannot use a nil pointer as query example")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot use %T as query example, a struct is required", v)
+	}
+
+	values := make([]exampleValue, 0)
+	for _, f := range structFields(rv.Type()) {
+		if f.expr != "" {
+			continue
+		}
+		fv, ok := exampleField(rv, f.index)
+		if !ok {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		} else if isZero(fv) {
+			continue
+		}
+		values = append(values, exampleValue{column: f.column, value: fv})
+	}
+	return values, nil
+}
+
+// exampleField returns the field at index, ok is false if it is inside a nil embedded struct pointer
+func exampleField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isZero(v reflect.Value) bool {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return v.IsZero()
+}
+
+// floatingTimestamp is the SoQL layout for timestamps without a timezone
+const floatingTimestamp = "2006-01-02T15:04:05.000"
+
+// Literal returns v formatted as a typed SoQL literal for use in a $where clause.
+// Strings are quoted and escaped, numbers and booleans are written as is,
+// time.Time values are written as floating timestamps (in the timezone of the value) and Points as WKT.
+// A nil pointer is written as NULL.
+func Literal(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "NULL", nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return Literal(rv.Elem().Interface())
+	}
+
+	switch val := v.(type) {
+	case time.Time:
+		return "'" + val.Format(floatingTimestamp) + "'", nil
+	case Point:
+		return fmt.Sprintf("'POINT (%s %s)'", strconv.FormatFloat(val.Longitude, 'f', -1, 64), strconv.FormatFloat(val.Latitude, 'f', -1, 64)), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return "'" + strings.Replace(rv.String(), "'", "''", -1) + "'", nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("cannot use %T as SoQL literal", v)
+}
+
+// WhereByExample returns a $where clause matching all non-zero soda fields of struct v for equality,
+// combined using AND. Values are written as typed SoQL literals, see Literal.
+// Use pointer fields to match on zero values, nil pointers are ignored.
+func WhereByExample(v interface{}) (string, error) {
+	values, err := exampleValues(v)
+	if err != nil {
+		return "", err
+	}
+	conds := make([]string, len(values))
+	for i, ev := range values {
+		lit, err := Literal(ev.value.Interface())
+		if err != nil {
+			return "", fmt.Errorf("cannot use column %s: %v", ev.column, err)
+		}
+		conds[i] = fmt.Sprintf("%s = %s", ev.column, lit)
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+// FiltersByExample returns SimpleFilters matching all non-zero soda fields of struct v for equality.
+// Use pointer fields to match on zero values, nil pointers are ignored.
+func FiltersByExample(v interface{}) (SimpleFilters, error) {
+	values, err := exampleValues(v)
+	if err != nil {
+		return nil, err
+	}
+	sf := make(SimpleFilters)
+	for _, ev := range values {
+		s, err := filterValue(ev.value.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("cannot use column %s: %v", ev.column, err)
+		}
+		sf[ev.column] = s
+	}
+	return sf, nil
+}
+
+// filterValue returns v formatted as a SimpleFilters value (which is not quoted)
+func filterValue(v interface{}) (string, error) {
+	if val, ok := v.(time.Time); ok {
+		return val.Format(floatingTimestamp), nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	lit, err := Literal(v)
+	if err != nil {
+		return "", err
+	}
+	if len(lit) >= 2 && strings.HasPrefix(lit, "'") && strings.HasSuffix(lit, "'") {
+		lit = strings.Replace(lit[1:len(lit)-1], "''", "'", -1)
+	}
+	return lit, nil
+}
@@ -0,0 +1,182 @@
+package soda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type farmItems struct {
+	FarmName string    `soda:"farm_name"`
+	Zipcode  *int      `soda:"zipcode"`
+	Items    uint      `soda:"items,expr=count(item)"`
+	Updated  time.Time `soda:"updated,expr=max(coalesce(updated, created))"`
+	Phone    string    `json:"phone1"`
+	Ignored  string    `soda:"-"`
+	Untagged string
+}
+
+func TestStructSelect(t *testing.T) {
+
+	sel, err := StructSelect([]farmItems{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"farm_name", "zipcode", "count(item) AS items", "max(coalesce(updated, created)) AS updated", "phone1"}
+	if !reflect.DeepEqual(sel, want) {
+		t.Errorf("Want %v, have %v", want, sel)
+	}
+
+	if _, err := StructSelect("farm_name"); err == nil {
+		t.Error("Wanted error for a non-struct type")
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := "count(item) AS items"
+		if sel := r.URL.Query().Get("$select"); !strings.Contains(sel, want) {
+			t.Errorf("Want %s in $select, have %s", want, sel)
+		}
+		w.Write([]byte(`[
+			{"farm_name":"Bell Nurseries","zipcode":"06037","items":"12","updated":"2014-09-04T15:01:44.000"},
+			{"farm_name":"Beaver Brook Farm","items":"3","phone1":"555-1234"}
+		]`))
+	}))
+	defer ts.Close()
+
+	gr, err := NewGetRequestFor(ts.URL+"/resource/hma6-9xbg", apptoken, farmItems{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr.Query.Group = "farm_name,zipcode,phone1"
+
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	results := make([]*farmItems, 0)
+	if err := DecodeJSON(resp.Body, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Want %d results, have %d", 2, len(results))
+	}
+	if results[0].Zipcode == nil || *results[0].Zipcode != 6037 || results[0].Items != 12 {
+		t.Errorf("Unexpected result %+v", results[0])
+	}
+	if !results[0].Updated.Equal(time.Date(2014, 9, 4, 15, 1, 44, 0, time.UTC)) {
+		t.Errorf("Unexpected updated time %s", results[0].Updated)
+	}
+	if results[1].Zipcode != nil || results[1].Phone != "555-1234" {
+		t.Errorf("Unexpected result %+v", results[1])
+	}
+}
+
+type embeddedInner struct {
+	A string `soda:"a"`
+}
+
+func TestDecodeJSONUnexportedEmbedded(t *testing.T) {
+
+	type outer struct {
+		*embeddedInner
+		B string `soda:"b"`
+	}
+
+	results := make([]outer, 0)
+	if err := DecodeJSON(strings.NewReader(`[{"b":"2"}]`), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].B != "2" || results[0].embeddedInner != nil {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	err := DecodeJSON(strings.NewReader(`[{"a":"1","b":"2"}]`), &results)
+	if err == nil || !strings.Contains(err.Error(), "unexported struct") {
+		t.Errorf("Want error for an unexported embedded pointer, have %v", err)
+	}
+}
+
+func TestWhereByExample(t *testing.T) {
+
+	zip := 0
+	ex := farmItems{
+		FarmName: "Farmer's Market",
+		Zipcode:  &zip,
+		Items:    5, //computed, ignored
+		Phone:    "",
+	}
+	where, err := WhereByExample(&ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "farm_name = 'Farmer''s Market' AND zipcode = 0"
+	if where != want {
+		t.Errorf("Want %s, have %s", want, where)
+	}
+
+	filters, err := FiltersByExample(ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFilters := SimpleFilters{"farm_name": "Farmer's Market", "zipcode": "0"}
+	if !reflect.DeepEqual(filters, wantFilters) {
+		t.Errorf("Want %v, have %v", wantFilters, filters)
+	}
+}
+
+func TestFiltersByExampleQuotes(t *testing.T) {
+
+	type name string
+	owner := "'Owner'"
+	ex := struct {
+		Name     name    `soda:"name"`
+		Owner    *string `soda:"owner"`
+		Location Point   `soda:"location"`
+	}{"O'Brien", &owner, Point{Longitude: -72.1, Latitude: 41.3}}
+
+	filters, err := FiltersByExample(ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SimpleFilters{"name": "O'Brien", "owner": "'Owner'", "location": "POINT (-72.1 41.3)"}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("Want %v, have %v", want, filters)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{"it's", "'it''s'"},
+		{42, "42"},
+		{uint(7), "7"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{time.Date(2014, 9, 4, 15, 1, 44, 0, time.UTC), "'2014-09-04T15:01:44.000'"},
+		{Point{Longitude: -72.1, Latitude: 41.3}, "'POINT (-72.1 41.3)'"},
+		{(*int)(nil), "NULL"},
+	}
+	for _, test := range tests {
+		have, err := Literal(test.v)
+		if err != nil {
+			t.Errorf("Literal(%v): %v", test.v, err)
+			continue
+		}
+		if have != test.want {
+			t.Errorf("Want %s, have %s", test.want, have)
+		}
+	}
+	if _, err := Literal([]string{}); err == nil {
+		t.Error("Wanted error for a slice")
+	}
+}
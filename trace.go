@@ -0,0 +1,142 @@
+package soda
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span describes a single SODA call for tracing and logging.
+// It is complete when the response body is read to the end or closed, or when the call failed.
+type Span struct {
+	Op         string        //Operation name, see the Op constants
+	Dataset    string        //Dataset identifier, for example hma6-9xbg
+	Method     string        //HTTP method
+	URL        string        //Request URL without query
+	Query      url.Values    //Query parameters, with secrets redacted
+	Start      time.Time     //Start of the call
+	Duration   time.Duration //Duration of the call, including reading the response body
+	StatusCode int           //Response status, 0 when no response was received
+	Attempts   int           //Number of HTTP requests done, including retries and polls
+	Bytes      int64         //Number of response body bytes read
+	Err        error         //Error of the call or of reading the response body
+}
+
+// TraceHooks are called at the start and end of every SODA call, use them to connect a tracer.
+// Both hooks are optional.
+type TraceHooks struct {
+	//Start is called before the call, the returned context is used for the HTTP request
+	//(for example to propagate the span to an http.RoundTripper). The span is not complete yet.
+	Start func(ctx context.Context, span *Span) context.Context
+
+	//End is called when the span is complete with the context returned by Start
+	End func(ctx context.Context, span *Span)
+}
+
+// TraceInterceptor returns an Interceptor calling hooks for every call
+func TraceInterceptor(hooks TraceHooks) Interceptor {
+	return func(call *Call, next Handler) (*http.Response, error) {
+		span := newSpan(call)
+		ctx := call.HTTPRequest.Context()
+		if hooks.Start != nil {
+			ctx = hooks.Start(ctx, span)
+			call.HTTPRequest = call.HTTPRequest.WithContext(ctx)
+		}
+		return observe(call, next, span, func(span *Span) {
+			if hooks.End != nil {
+				hooks.End(ctx, span)
+			}
+		})
+	}
+}
+
+// newSpan creates a span for call
+func newSpan(call *Call) *Span {
+	u := *call.HTTPRequest.URL
+	u.RawQuery = ""
+	span := &Span{
+		Op:     call.Op,
+		Method: call.HTTPRequest.Method,
+		URL:    u.String(),
+		Query:  redactQuery(call.HTTPRequest.URL.Query()),
+		Start:  time.Now(),
+	}
+	if span.Method == "" {
+		span.Method = "GET"
+	}
+	if call.Request != nil {
+		span.Dataset = call.Request.Metadata.identifier
+	}
+	return span
+}
+
+// observe executes next and calls done with the completed span,
+// for a successful call when the response body is read to the end or closed
+func observe(call *Call, next Handler, span *Span, done func(span *Span)) (*http.Response, error) {
+	resp, err := next(call)
+	span.Attempts = call.Attempts
+	if resp != nil {
+		span.StatusCode = resp.StatusCode
+	}
+	var serr *Error
+	if errors.As(err, &serr) {
+		span.StatusCode = serr.StatusCode
+	}
+	if err != nil || resp == nil {
+		span.Err = err
+		span.Duration = time.Since(span.Start)
+		done(span)
+		return resp, err
+	}
+	resp.Body = &spanBody{wrappedBody: wrappedBody{ReadCloser: resp.Body}, span: span, done: done}
+	return resp, nil
+}
+
+// spanBody is a response body completing its span at the end
+type spanBody struct {
+	wrappedBody
+	span *Span
+	done func(span *Span)
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.wrappedBody.Read(p)
+	b.span.Bytes += int64(n)
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *spanBody) finish(err error) {
+	b.once.Do(func() {
+		if err != io.EOF {
+			b.span.Err = err
+		}
+		b.span.Duration = time.Since(b.span.Start)
+		b.done(b.span)
+	})
+}
+
+// redactQuery returns q with the values of parameters containing secrets replaced
+func redactQuery(q url.Values) url.Values {
+	for key := range q {
+		lk := strings.ToLower(key)
+		if strings.Contains(lk, "token") || strings.Contains(lk, "secret") || strings.Contains(lk, "password") {
+			q[key] = []string{"REDACTED"}
+		}
+	}
+	return q
+}
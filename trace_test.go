@@ -0,0 +1,128 @@
+package soda
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+// traceTransport checks that the context returned by TraceHooks.Start is used for the HTTP request
+type traceTransport struct {
+	t *testing.T
+}
+
+func (tt traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(traceKey{}) != "span" {
+		tt.t.Errorf("Want trace context for %s", req.URL)
+	}
+	if Operation(req.Context()) == "" {
+		tt.t.Error("Want operation in context")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTraceInterceptor(t *testing.T) {
+
+	ds := newTestDataset(10)
+	failures := int32(1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	spans := make([]Span, 0)
+	gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+	gr.HTTPClient = &http.Client{Transport: traceTransport{t}}
+	gr.Retry = &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}
+	gr.Filters["$$app_token"] = "secret"
+	gr.Interceptors = []Interceptor{TraceInterceptor(TraceHooks{
+		Start: func(ctx context.Context, span *Span) context.Context {
+			return context.WithValue(ctx, traceKey{}, "span")
+		},
+		End: func(ctx context.Context, span *Span) {
+			if ctx.Value(traceKey{}) != "span" {
+				t.Error("Want trace context in End")
+			}
+			spans = append(spans, *span)
+		},
+	})}
+
+	resp, err := gr.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("Want span to end after reading the body, have %d spans", len(spans))
+	}
+	resp.Body.Close()
+	if len(spans) != 1 {
+		t.Fatalf("Want span to end once, have %d spans", len(spans))
+	}
+
+	span := spans[0]
+	if span.Op != OpGet || span.Dataset != "hma6-9xbg" || span.URL != ts.URL+"/resource/hma6-9xbg.json" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+	if span.StatusCode != 200 || span.Attempts != 2 || span.Bytes != int64(len(body)) || span.Err != nil {
+		t.Errorf("Want status 200, 2 attempts and %d bytes, have %+v", len(body), span)
+	}
+	if span.Query.Get("$$app_token") != "REDACTED" {
+		t.Errorf("Want app token redacted, have %s", span.Query.Get("$$app_token"))
+	}
+
+	ts.Config.Handler = http.NotFoundHandler()
+	if _, err := gr.Metadata.Get(); err == nil {
+		t.Fatal("Want error")
+	}
+	span = spans[len(spans)-1]
+	if span.Op != OpMetadata || span.StatusCode != 404 || !errors.Is(span.Err, ErrNotFound) {
+		t.Errorf("Want failed metadata span, have %+v", span)
+	}
+}
+
+func TestTraceInterceptorCompressed(t *testing.T) {
+
+	ds := newTestDataset(3000)
+	ts := httptest.NewServer(compressHandler(ds, "gzip"))
+	defer ts.Close()
+
+	for _, limit := range []uint{500, 3000} {
+		var last Progress
+		gr := NewGetRequest(ts.URL+"/resource/hma6-9xbg", apptoken)
+		gr.HTTPClient = &http.Client{Transport: &http.Transport{DisableCompression: true}}
+		gr.Query.Limit = limit
+		gr.MaxLimit = 1000
+		gr.Progress = func(p Progress) {
+			last = p
+		}
+		gr.Interceptors = []Interceptor{TraceInterceptor(TraceHooks{})}
+
+		resp, err := gr.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last.WireBytes == 0 || last.WireBytes*2 > last.Bytes {
+			t.Errorf("Limit %d: want compressed wire bytes, have %d wire and %d decoded bytes", limit, last.WireBytes, last.Bytes)
+		}
+	}
+}